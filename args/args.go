@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // DefaultArgumentList includes the minimal set of necessary arguments for an integration.
@@ -22,6 +23,8 @@ type DefaultArgumentList struct {
 	NriCluster string `default:"" help:"Optional. Cluster name"`
 	NriService string `default:"" help:"Optional. Service name"`
 	NriHostID  string `default:"" help:"Optional. Host ID to be set in entity or/and in the payload"`
+	ConfigPath string `default:"" help:"Optional. Path to a YAML or JSON file with the arguments"`
+	ShowConfig bool   `default:"false" help:"Print the effective arguments, with secrets redacted, and exit."`
 }
 
 // All returns if all data should be published
@@ -45,22 +48,118 @@ func (d *DefaultArgumentList) HasInventory() bool {
 }
 
 // HTTPClientArgumentList are meant to be used as flags from a custom integrations. With this you could
-// send this arguments from the command line.
+// send this arguments from the command line. The http.FromArgs option configures a client from them.
 type HTTPClientArgumentList struct {
-	HTTPCaBundleFile string `default:"" help:"Name of the certificate file"`
-	HTTPCaBundleDir  string `default:"" help:"Path where the certificate exists"`
-	HTTPTimeout      int    `default:"30" help:"Client http timeout in seconds"`
+	HTTPCaBundleFile   string   `default:"" help:"Name of the certificate file"`
+	HTTPCaBundleDir    string   `default:"" help:"Path where the certificate exists"`
+	HTTPTimeout        int      `default:"30" help:"Client http timeout in seconds"`
+	HTTPClientCertFile string   `default:"" help:"Client certificate file for mutual TLS"`
+	HTTPClientKeyFile  string   `default:"" help:"Client key file for mutual TLS"`
+	HTTPMinTLSVersion  string   `default:"" enum:"1.0,1.1,1.2,1.3" help:"Minimum TLS version: 1.0, 1.1, 1.2 or 1.3"`
+	HTTPCipherSuites   []string `help:"Comma-separated TLS cipher suites, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"`
+	HTTPProxy          string   `default:"" help:"Proxy URL for the http requests"`
+	HTTPNoProxy        string   `default:"" help:"Comma-separated hosts, domains or CIDRs not using the proxy"`
+	// at most one authentication method can be set
+	HTTPUsername        string            `default:"" exclusive:"http_auth" help:"Username for basic authentication"`
+	HTTPPassword        string            `default:"" secret:"true" help:"Password for basic authentication"`
+	HTTPBearerToken     string            `default:"" exclusive:"http_auth" secret:"true" help:"Bearer token"`
+	HTTPBearerTokenFile string            `default:"" exclusive:"http_auth" help:"File with the bearer token, read again when modified"`
+	HTTPHeaders         map[string]string `help:"Comma-separated Name=value headers set on every request"`
+	// retries of the failed idempotent requests and per host circuit breaker, disabled by default
+	HTTPRetries          int           `default:"0" min:"0" help:"Times a failed idempotent request is retried"`
+	HTTPRetryBackoff     time.Duration `default:"1s" help:"Wait before the first retry, doubled on every further retry"`
+	HTTPRetryMaxBackoff  time.Duration `default:"30s" help:"Maximum wait between retries, and maximum Retry-After honored"`
+	HTTPBreakerThreshold int           `default:"0" min:"0" help:"Consecutive failed requests to a host opening its circuit, 0 to disable"`
+	HTTPBreakerCooldown  time.Duration `default:"30s" help:"Time requests to a host are rejected once its circuit is open"`
 }
 
-func getArgsFromEnv() func(f *flag.Flag) {
-	return func(f *flag.Flag) {
-		envName := strings.ToUpper(f.Name)
-		if os.Getenv(envName) != "" {
-			f.Value.Set(os.Getenv(envName)) // nolint: errcheck
+// setup holds where SetupArgs defines and parses the arguments from.
+type setup struct {
+	flags          *flag.FlagSet
+	argv           []string
+	getenv         func(string) string
+	secretCommands bool
+	envPrefix      string
+	legacyEnv      bool
+	// environment variable names set through tags, by argument name
+	envTags map[string]string
+	// names of the arguments holding secrets
+	secretArgs map[string]bool
+}
+
+func newSetup(opts []SetupOption) *setup {
+	s := &setup{
+		flags:      flag.CommandLine,
+		argv:       os.Args[1:],
+		getenv:     os.Getenv,
+		secretArgs: map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetupOption changes where SetupArgs defines and parses the arguments from.
+type SetupOption func(*setup)
+
+// FlagSet defines the arguments in the given flag set instead of flag.CommandLine, so they don't clash with
+// the arguments defined by other calls to SetupArgs.
+func FlagSet(fs *flag.FlagSet) SetupOption {
+	return func(s *setup) {
+		s.flags = fs
+	}
+}
+
+// Argv parses the given command-line arguments, without the program name, instead of os.Args[1:].
+func Argv(argv []string) SetupOption {
+	return func(s *setup) {
+		s.argv = argv
+	}
+}
+
+// Environ reads the arguments from the given environment variables, in the "KEY=value" form returned by
+// os.Environ, instead of the process environment.
+func Environ(environ []string) SetupOption {
+	return func(s *setup) {
+		env := make(map[string]string, len(environ))
+		for _, e := range environ {
+			kv := strings.SplitN(e, "=", 2)
+			if len(kv) == 2 {
+				env[kv[0]] = kv[1]
+			}
+		}
+		s.getenv = func(key string) string {
+			return env[key]
 		}
 	}
 }
 
+// getArgsFromEnv sets the flags not set yet from their environment variables (see envNames), recording
+// their source. A ValidationError is returned listing every environment variable with an invalid value.
+func (s *setup) getArgsFromEnv(sources map[string]Source) error {
+	var errs ValidationError
+	s.flags.VisitAll(func(f *flag.Flag) {
+		if _, ok := sources[f.Name]; ok {
+			return
+		}
+		value := s.lookupEnv(f.Name, "")
+		if value == "" {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, ArgumentError{Name: f.Name, Source: SourceEnv, Reason: "invalid value, " + err.Error()})
+			return
+		}
+		sources[f.Name] = SourceEnv
+	})
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 var camel = regexp.MustCompile("(^[^A-Z]*|[A-Z]*)([A-Z][^A-Z]+|$)")
 
 func underscore(s string) string {
@@ -88,21 +187,107 @@ func underscore(s string) string {
 //	}
 //
 // The fields in the struct will be populated with the values set either from
-// the command line or from environment variables.
-func SetupArgs(args interface{}) error {
-	err := defineFlags(args)
+// the command line, from environment variables or from the configuration file
+// set by the ConfigPath argument (see DefaultArgumentList), in that order of
+// precedence, falling back to the default values. Arguments are read from
+// the environment variables with their upper-cased name, e.g. PASSWORD for
+// the password argument, which can be prefixed through EnvPrefix or set
+// through the `env:"NAME"` tag:
+//
+//	type Arguments struct {
+//	   	Password string `env:"MYSQL_PASSWORD" help:"This is the help we will print"`
+//	}
+//
+// An argument can also be read from the file pointed by its environment
+// variable with the _FILE suffix, e.g. PASSWORD_FILE for the password
+// argument, with the precedence of environment variables.
+//
+// Values of string, list and map arguments can hold secret references, which
+// are resolved before validation:
+//
+//	${env:VAR}          the value of the environment variable
+//	${file:/path}       the content of the file, without trailing new lines
+//	${cmd:command args} the output of the command, if enabled (see SecretCommands)
+//
+// Values read from files or resolved from references are considered secrets
+// (see SecretValues), as well as the values of the fields with the
+// `secret:"true"` tag.
+//
+// If the ShowConfig argument (see DefaultArgumentList) is set, the effective
+// arguments are printed instead of validated, see ErrShowConfig.
+//
+// Once populated, the arguments are validated according to the validation
+// tags of their fields, returning a ValidationError listing every invalid
+// argument:
+//
+//	type Arguments struct {
+//	   	Hostname string `required:"true" help:"This is the help we will print"`
+//	   	Port     int    `default:"3306" min:"1" max:"65535" help:"This is the help we will print"`
+//	   	Mode     string `default:"fast" enum:"fast,safe" help:"This is the help we will print"`
+//	   	Password string `exclusive:"auth" help:"This is the help we will print"`
+//	   	Token    string `exclusive:"auth" help:"This is the help we will print"`
+//	}
+//
+// Supported validation tags are:
+//
+//	required:"true"   the value can't be the zero value of its type
+//	min:"1" max:"10"  bounds of numeric values (durations are bound by durations, e.g. min:"1s"),
+//	                  or length bounds of strings, lists and maps
+//	enum:"a,b,c"      allowed values of strings, or of every element of lists
+//	regex:"[a-z]+"    regular expression strings, or every element of lists, must fully match
+//	exclusive:"group" at most one argument of the group can be set to a non-zero value
+//
+// Empty strings, lists and maps are only checked by the required tag.
+//
+// By default, arguments are defined in flag.CommandLine and parsed from
+// os.Args and the process environment, which can be changed through options.
+func SetupArgs(args interface{}, opts ...SetupOption) error {
+	s := newSetup(opts)
+
+	err := defineFlags(s.flags, args)
 	if err != nil {
 		return err
 	}
 
-	// Override flags from environment variables with the same name
-	flag.VisitAll(getArgsFromEnv())
+	if err := s.flags.Parse(s.argv); err != nil {
+		return err
+	}
+
+	sources := map[string]Source{}
+	s.flags.Visit(func(f *flag.Flag) {
+		sources[f.Name] = SourceFlag
+	})
 
-	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+	// Override flags not set from the command line from environment variables
+	s.envTags = envTags(args)
+	if err := s.getArgsFromEnv(sources); err != nil {
 		return err
 	}
 
-	return nil
+	// Then from the files pointed by environment variables with the _FILE suffix
+	if err := s.getArgsFromEnvFiles(sources); err != nil {
+		return err
+	}
+
+	// And the remaining ones from the configuration file, if any
+	if f := s.flags.Lookup(configPathFlag); f != nil && f.Value.String() != "" {
+		if err := loadConfigFile(s.flags, f.Value.String(), sources); err != nil {
+			return err
+		}
+	}
+
+	if err := s.resolveSecrets(args); err != nil {
+		return err
+	}
+	if err := s.markTaggedSecrets(args); err != nil {
+		return err
+	}
+
+	if f := s.flags.Lookup(showConfigFlag); f != nil && f.Value.String() == "true" {
+		return s.showConfig(sources)
+	}
+
+	return validate(args, sources)
 }
 
 // GetDefaultArgs checks if the arguments interface contains a
@@ -122,7 +307,15 @@ func GetDefaultArgs(arguments interface{}) *DefaultArgumentList {
 	return &DefaultArgumentList{}
 }
 
-func defineFlags(args interface{}) error {
+func defineFlags(fs *flag.FlagSet, args interface{}) error {
+	return defineFlagsWithPrefix(fs, args, "")
+}
+
+// defineFlagsWithPrefix defines a flag for every field of the struct pointed by args, prefixing their names.
+// Fields of embedded structs are defined as if they were fields of the outer struct, while fields of nested
+// structs are prefixed by the name of the nested struct field, e.g. the Port field of a Database field is
+// defined as "database_port".
+func defineFlagsWithPrefix(fs *flag.FlagSet, args interface{}, prefix string) error {
 	val := reflect.ValueOf(args).Elem()
 
 	for i := 0; i < val.NumField(); i++ {
@@ -131,38 +324,104 @@ func defineFlags(args interface{}) error {
 		tag := typeField.Tag
 
 		// The argument will take the field's name in underscore
-		argName := underscore(typeField.Name)
+		argName := prefix + underscore(typeField.Name)
 		// We get a generic pointer to the field
 		argDefault := valueField.Addr().Interface()
 		// Get the default and help tags fom the struct field
 		defaultValue := tag.Get("default")
 		helpValue := tag.Get("help")
 
+		if tag.Get("format") == "json" {
+			v := newTypedJSON(valueField)
+			if defaultValue != "" {
+				if err := v.Set(defaultValue); err != nil {
+					return fmt.Errorf("can't parse %s: %s", argName, err)
+				}
+			}
+			fs.Var(v, argName, helpValue)
+			continue
+		}
+
 		switch argDefault := argDefault.(type) {
 		case *int:
 			intVal, err := strconv.Atoi(defaultValue)
 			if err != nil {
 				return fmt.Errorf("can't parse %s: not an integer", argName)
 			}
-			flag.IntVar(argDefault, argName, intVal, helpValue)
+			fs.IntVar(argDefault, argName, intVal, helpValue)
 		case *bool:
 			boolVal, err := strconv.ParseBool(defaultValue)
 			if err != nil {
 				return fmt.Errorf("can't parse %s: not a boolean", argName)
 			}
-			flag.BoolVar(argDefault, argName, boolVal, helpValue)
+			fs.BoolVar(argDefault, argName, boolVal, helpValue)
 		case *string:
-			flag.StringVar(argDefault, argName, defaultValue, helpValue)
-		case *JSON:
-			jsonVar(argDefault, argName, helpValue)
-		case *DefaultArgumentList, *HTTPClientArgumentList:
-			err := defineFlags(argDefault)
+			fs.StringVar(argDefault, argName, defaultValue, helpValue)
+		case *float64:
+			floatVal, err := strconv.ParseFloat(orZero(defaultValue), 64)
 			if err != nil {
-				return err
+				return fmt.Errorf("can't parse %s: not a float", argName)
+			}
+			fs.Float64Var(argDefault, argName, floatVal, helpValue)
+		case *int64:
+			intVal, err := strconv.ParseInt(orZero(defaultValue), 10, 64)
+			if err != nil {
+				return fmt.Errorf("can't parse %s: not an integer", argName)
+			}
+			fs.Int64Var(argDefault, argName, intVal, helpValue)
+		case *uint:
+			uintVal, err := strconv.ParseUint(orZero(defaultValue), 10, 0)
+			if err != nil {
+				return fmt.Errorf("can't parse %s: not an unsigned integer", argName)
+			}
+			fs.UintVar(argDefault, argName, uint(uintVal), helpValue)
+		case *time.Duration:
+			durationVal, err := time.ParseDuration(orZero(defaultValue))
+			if err != nil {
+				return fmt.Errorf("can't parse %s: not a duration", argName)
+			}
+			fs.DurationVar(argDefault, argName, durationVal, helpValue)
+		case *[]string:
+			v := newStringSlice(argDefault)
+			if err := v.setDefault(defaultValue); err != nil {
+				return fmt.Errorf("can't parse %s: %s", argName, err)
 			}
+			fs.Var(v, argName, helpValue)
+		case *map[string]string:
+			v := newStringMap(argDefault)
+			if err := v.setDefault(defaultValue); err != nil {
+				return fmt.Errorf("can't parse %s: %s", argName, err)
+			}
+			fs.Var(v, argName, helpValue)
+		case *JSON:
+			jsonVar(fs, argDefault, argName, helpValue)
+		case flag.Value:
+			if defaultValue != "" {
+				if err := argDefault.Set(defaultValue); err != nil {
+					return fmt.Errorf("can't parse %s: %s", argName, err)
+				}
+			}
+			fs.Var(argDefault, argName, helpValue)
 		default:
-			return fmt.Errorf("can't parse %s: unsupported type", argName)
+			if !isArgsStruct(valueField) {
+				return fmt.Errorf("can't parse %s: unsupported type", argName)
+			}
+			nestedPrefix := argName + "_"
+			if typeField.Anonymous {
+				nestedPrefix = prefix
+			}
+			if err := defineFlagsWithPrefix(fs, argDefault, nestedPrefix); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
+
+// orZero returns "0" for empty default values, so numeric arguments default to zero if no default is set.
+func orZero(defaultValue string) string {
+	if defaultValue == "" {
+		return "0"
+	}
+	return defaultValue
+}
@@ -2,9 +2,13 @@ package args_test
 
 import (
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
 	"github.com/stretchr/testify/assert"
@@ -233,11 +237,213 @@ func TestSetupArgsErrors(t *testing.T) {
 	assert.Error(t, sdk_args.SetupArgs(&argumentList2{}))
 
 	type argumentList3 struct {
-		Verbose float64 `default:"0.12" help:"Print more information to logs."`
+		Verbose float64 `default:"badfloat" help:"Print more information to logs."`
 	}
 
 	clearFlagSet()
 	assert.Error(t, sdk_args.SetupArgs(&argumentList3{}))
+
+	type argumentList4 struct {
+		Verbose complex128 `default:"1" help:"Print more information to logs."`
+	}
+
+	clearFlagSet()
+	assert.Error(t, sdk_args.SetupArgs(&argumentList4{}))
+
+	type argumentList5 struct {
+		Labels map[string]string `default:"novalue" help:""`
+	}
+
+	clearFlagSet()
+	assert.Error(t, sdk_args.SetupArgs(&argumentList5{}))
+
+	// structs with unexported fields aren't nested arguments
+	type argumentList6 struct {
+		Since time.Time `help:""`
+	}
+
+	clearFlagSet()
+	assert.EqualError(t, sdk_args.SetupArgs(&argumentList6{}), "can't parse since: unsupported type")
+	_, err = sdk_args.Describe(&argumentList6{})
+	assert.EqualError(t, err, "can't parse since: unsupported type")
+}
+
+type logLevel string
+
+func (l *logLevel) Set(s string) error {
+	if s != "debug" && s != "info" {
+		return fmt.Errorf("unknown log level %s", s)
+	}
+	*l = logLevel(s)
+	return nil
+}
+
+func (l *logLevel) String() string {
+	if l == nil {
+		return ""
+	}
+	return string(*l)
+}
+
+type richArgumentList struct {
+	Ratio     float64           `default:"0.5" help:""`
+	MaxBytes  int64             `default:"1099511627776" help:""`
+	Workers   uint              `default:"4" help:""`
+	Timeout   time.Duration     `default:"30s" help:""`
+	Databases []string          `default:"db1,db2" help:""`
+	Labels    map[string]string `default:"env=dev,team=core" help:""`
+	Level     logLevel          `default:"info" help:""`
+	Unset     float64           `help:""`
+	Database  struct {
+		Host string `default:"localhost" help:""`
+		Port int    `default:"3306" help:""`
+	}
+}
+
+func TestSetupArgsRichTypesDefaults(t *testing.T) {
+	var args richArgumentList
+	os.Args = []string{"cmd"}
+
+	clearFlagSet()
+	assert.NoError(t, sdk_args.SetupArgs(&args))
+
+	assert.Equal(t, 0.5, args.Ratio)
+	assert.Equal(t, int64(1099511627776), args.MaxBytes)
+	assert.Equal(t, uint(4), args.Workers)
+	assert.Equal(t, 30*time.Second, args.Timeout)
+	assert.Equal(t, []string{"db1", "db2"}, args.Databases)
+	assert.Equal(t, map[string]string{"env": "dev", "team": "core"}, args.Labels)
+	assert.Equal(t, logLevel("info"), args.Level)
+	assert.Equal(t, float64(0), args.Unset)
+	assert.Equal(t, "localhost", args.Database.Host)
+	assert.Equal(t, 3306, args.Database.Port)
+}
+
+func TestSetupArgsRichTypes(t *testing.T) {
+	var args richArgumentList
+	_ = os.Setenv("DATABASE_HOST", "envhost")
+	defer func() { _ = os.Unsetenv("DATABASE_HOST") }()
+	os.Args = []string{
+		"cmd",
+		"-ratio=0.25",
+		"-max_bytes=-1",
+		"-workers=8",
+		"-timeout=1m30s",
+		"-databases=db3, db4",
+		"-databases=db5",
+		"-labels=env=prod",
+		"-level=debug",
+		"-database_port=5432",
+	}
+
+	clearFlagSet()
+	assert.NoError(t, sdk_args.SetupArgs(&args))
+
+	assert.Equal(t, 0.25, args.Ratio)
+	assert.Equal(t, int64(-1), args.MaxBytes)
+	assert.Equal(t, uint(8), args.Workers)
+	assert.Equal(t, 90*time.Second, args.Timeout)
+	assert.Equal(t, []string{"db3", "db4", "db5"}, args.Databases)
+	assert.Equal(t, map[string]string{"env": "prod"}, args.Labels)
+	assert.Equal(t, logLevel("debug"), args.Level)
+	assert.Equal(t, "envhost", args.Database.Host)
+	assert.Equal(t, 5432, args.Database.Port)
+}
+
+func TestSetupArgsRichTypesConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "args")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "config.yml")
+	content := "databases: [db3, db4]\nlabels:\n  env: prod\ndatabase:\n  host: filehost\ndatabase_port: 5432\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	var args struct {
+		sdk_args.DefaultArgumentList
+		Databases []string          `help:""`
+		Labels    map[string]string `help:""`
+		Database  struct {
+			Host string `default:"localhost" help:""`
+			Port int    `default:"3306" help:""`
+		}
+	}
+	os.Args = []string{"cmd", "-config_path=" + path}
+
+	clearFlagSet()
+	assert.NoError(t, sdk_args.SetupArgs(&args))
+
+	assert.Equal(t, []string{"db3", "db4"}, args.Databases)
+	assert.Equal(t, map[string]string{"env": "prod"}, args.Labels)
+	assert.Equal(t, "filehost", args.Database.Host)
+	assert.Equal(t, 5432, args.Database.Port)
+}
+
+func TestSetupArgsConfigFile(t *testing.T) {
+	type argumentList struct {
+		sdk_args.DefaultArgumentList
+		Hostname string        `default:"localhost" help:""`
+		Port     int           `default:"3306" help:""`
+		Username string        `default:"root" help:""`
+		Timeout  int           `default:"10" help:""`
+		Config   sdk_args.JSON `help:""`
+	}
+
+	dir, err := ioutil.TempDir("", "args")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files := map[string]string{
+		"config.yml": "hostname: filehost\nport: 1234\nusername: fileuser\npretty: true\nconfig:\n  databases: [db1, db2]\n",
+		"config.json": `{"hostname": "filehost", "port": 1234, "username": "fileuser", "pretty": true,
+			"config": {"databases": ["db1", "db2"]}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+		_ = os.Setenv("USERNAME", "envuser")
+		_ = os.Setenv("PORT", "5678")
+		os.Args = []string{"cmd", "-config_path=" + path, "-port=9999"}
+
+		var args argumentList
+		clearFlagSet()
+		assert.NoError(t, sdk_args.SetupArgs(&args), name)
+		_ = os.Unsetenv("USERNAME")
+		_ = os.Unsetenv("PORT")
+
+		assert.Equal(t, "filehost", args.Hostname, name)
+		assert.Equal(t, 9999, args.Port, "flags take precedence over env and file: %s", name)
+		assert.Equal(t, "envuser", args.Username, "env takes precedence over file: %s", name)
+		assert.Equal(t, 10, args.Timeout, "defaults are used if not set: %s", name)
+		assert.True(t, args.Pretty, name)
+		assert.Equal(t, map[string]interface{}{"databases": []interface{}{"db1", "db2"}}, args.Config.Get(), name)
+	}
+}
+
+func TestSetupArgsConfigFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "args")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files := map[string]string{
+		"unknown.yml":     "unknown: value\n",
+		"invalid.json":    `{"verbose": }`,
+		"unsupported.yml": "nri_cluster: [a, b]\n",
+		"badvalue.yml":    "verbose: notabool\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+		os.Args = []string{"cmd", "-config_path=" + path}
+		clearFlagSet()
+		assert.Error(t, sdk_args.SetupArgs(&sdk_args.DefaultArgumentList{}), name)
+	}
+
+	os.Args = []string{"cmd", "-config_path=" + filepath.Join(dir, "missing.yml")}
+	clearFlagSet()
+	assert.Error(t, sdk_args.SetupArgs(&sdk_args.DefaultArgumentList{}))
 }
 
 func TestSetupArgsParseJsonError(t *testing.T) {
@@ -257,6 +463,86 @@ func TestSetupArgsParseJsonError(t *testing.T) {
 	}
 }
 
+type database struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+func (d database) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("database name is required")
+	}
+	return nil
+}
+
+type typedJSONArgumentList struct {
+	Databases []database           `format:"json" default:"[{\"name\": \"default\", \"port\": 1}]" help:""`
+	Primary   database             `format:"json" help:""`
+	Labels    map[string]string    `format:"json" help:""`
+	Replicas  []*database          `format:"json" help:""`
+	Shards    map[string]*database `format:"json" help:""`
+}
+
+func TestSetupArgsTypedJSON(t *testing.T) {
+	var args typedJSONArgumentList
+	os.Args = []string{"cmd", `-primary={"name": "main", "port": 3306}`, `-labels={"env": "prod"}`}
+
+	clearFlagSet()
+	assert.NoError(t, sdk_args.SetupArgs(&args))
+
+	assert.Equal(t, []database{{Name: "default", Port: 1}}, args.Databases)
+	assert.Equal(t, database{Name: "main", Port: 3306}, args.Primary)
+	assert.Equal(t, map[string]string{"env": "prod"}, args.Labels)
+}
+
+func TestSetupArgsTypedJSONErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":           `-primary={"name": "main", "user": "root"}`,
+		"wrong type":              `-primary={"name": "main", "port": "3306"}`,
+		"validation":              `-databases=[{"port": 3306}]`,
+		"pointer list validation": `-replicas=[{"name": "replica"}, {"port": 3306}]`,
+		"pointer map validation":  `-shards={"shard": {"port": 3306}}`,
+		"bad JSON":                `-labels={"env"`,
+	}
+	for name, arg := range cases {
+		var args typedJSONArgumentList
+		os.Args = []string{"cmd", arg}
+
+		clearFlagSet()
+		flag.CommandLine.SetOutput(ioutil.Discard)
+		err := sdk_args.SetupArgs(&args)
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), "flag -", "%s: error names the flag", name)
+		}
+	}
+
+	var badDefault struct {
+		Primary database `format:"json" default:"{\"user\": \"root\"}" help:""`
+	}
+	clearFlagSet()
+	os.Args = []string{"cmd"}
+	assert.EqualError(t, sdk_args.SetupArgs(&badDefault), `can't parse primary: bad JSON, json: unknown field "user"`)
+}
+
+func TestJSONDecode(t *testing.T) {
+	var args struct {
+		Config sdk_args.JSON `help:""`
+	}
+	os.Args = []string{"cmd", `-config={"name": "main", "port": 3306}`}
+
+	clearFlagSet()
+	assert.NoError(t, sdk_args.SetupArgs(&args))
+
+	var db database
+	assert.NoError(t, args.Config.Decode(&db))
+	assert.Equal(t, database{Name: "main", Port: 3306}, db)
+
+	var other struct {
+		Name string `json:"name"`
+	}
+	assert.Error(t, args.Config.Decode(&other))
+}
+
 func TestDefaultArgumentsWithPretty(t *testing.T) {
 	clearFlagSet()
 	os.Args = []string{
@@ -0,0 +1,173 @@
+package args
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// helpCommand is the name of the command printing the usage of the commands.
+const helpCommand = "help"
+
+// Command is a mode of an integration binary, e.g. collect, discover or version, with its own arguments.
+type Command struct {
+	Name string
+	// Help is a short description of the command, shown in the usage.
+	Help string
+	// Args points to the struct the arguments of the command are parsed to (see SetupArgs), or is nil if the
+	// command takes no arguments.
+	Args interface{}
+}
+
+// SetupCommand selects the command named by the first command-line argument and sets up its arguments from the
+// remaining ones, as SetupArgs does, returning the selected command. The first command is selected if the
+// command-line is empty or starts with a flag, so an integration can add commands keeping its former invocation.
+//
+//	cmd, err := args.SetupCommand([]args.Command{
+//		{Name: "collect", Help: "Collect metrics and inventory.", Args: &collectArgs},
+//		{Name: "discover", Help: "Discover the instances to monitor.", Args: &discoverArgs},
+//		{Name: "version", Help: "Print the integration version."},
+//	})
+//
+// The arguments of each command are defined in a dedicated flag set, whose usage is generated from its arguments
+// struct. The help command prints the list of commands, or the usage of the command given as argument:
+//
+//	$ nri-mysql help
+//	$ nri-mysql help discover
+//
+// As with the -help flag, the process exits after printing the usage if the flag set exits on errors, which is
+// the case of flag.CommandLine, or flag.ErrHelp is returned otherwise. An unknown command is handled as an
+// invalid flag.
+func SetupCommand(commands []Command, opts ...SetupOption) (*Command, error) {
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("no commands to set up")
+	}
+	for _, c := range commands {
+		if c.Name == "" || c.Name == helpCommand || strings.HasPrefix(c.Name, "-") {
+			return nil, fmt.Errorf("invalid command name %q", c.Name)
+		}
+		if c.Args == nil {
+			continue
+		}
+		val := reflect.ValueOf(c.Args)
+		if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("arguments of command %s must be a pointer to a struct (or nil)", c.Name)
+		}
+	}
+
+	s := newSetup(opts)
+	name, argv := commands[0].Name, s.argv
+	if len(argv) > 0 && !strings.HasPrefix(argv[0], "-") {
+		name, argv = argv[0], argv[1:]
+	}
+
+	if name == helpCommand {
+		if len(argv) > 0 {
+			if c := findCommand(commands, argv[0]); c != nil {
+				s.commandFlagSet(*c).Usage()
+				return nil, s.exit(flag.ErrHelp)
+			}
+		}
+		s.commandsUsage(commands)
+		return nil, s.exit(flag.ErrHelp)
+	}
+
+	c := findCommand(commands, name)
+	if c == nil {
+		err := fmt.Errorf("unknown command %q", name)
+		_, _ = fmt.Fprintln(s.flags.Output(), err)
+		s.commandsUsage(commands)
+		return nil, s.exit(err)
+	}
+
+	selected := *c
+	if selected.Args == nil {
+		selected.Args = new(struct{})
+	}
+	opts = append(opts, FlagSet(s.commandFlagSet(selected)), Argv(argv))
+	return &selected, SetupArgs(selected.Args, opts...)
+}
+
+func findCommand(commands []Command, name string) *Command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// commandFlagSet returns a flag set for the command, named after it, with a usage generated from its arguments.
+// It handles errors and writes to the same output as the flag set of the setup.
+func (s *setup) commandFlagSet(c Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(s.flags.Name()+" "+c.Name, s.flags.ErrorHandling())
+	fs.SetOutput(s.flags.Output())
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage: %s [arguments]\n", fs.Name())
+		if c.Help != "" {
+			_, _ = fmt.Fprintf(out, "\n%s\n", c.Help)
+		}
+		// the arguments are described from the struct, as the flag set may not be defined yet
+		if c.Args == nil {
+			return
+		}
+		described := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+		described.SetOutput(out)
+		instance := reflect.New(reflect.ValueOf(c.Args).Elem().Type())
+		if defineFlags(described, instance.Interface()) != nil {
+			return
+		}
+		if hasFlags(described) {
+			_, _ = fmt.Fprintln(out, "\nArguments:")
+			described.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// commandsUsage prints the list of commands into the output of the flag set of the setup.
+func (s *setup) commandsUsage(commands []Command) {
+	out := s.flags.Output()
+	_, _ = fmt.Fprintf(out, "Usage: %s <command> [arguments]\n\nCommands:\n", s.flags.Name())
+
+	width := len(helpCommand)
+	for _, c := range commands {
+		if len(c.Name) > width {
+			width = len(c.Name)
+		}
+	}
+	for n, c := range commands {
+		help := c.Help
+		if n == 0 {
+			help = strings.TrimSpace(help + " (default)")
+		}
+		_, _ = fmt.Fprintf(out, "  %-*s  %s\n", width, c.Name, help)
+	}
+	_, _ = fmt.Fprintf(out, "  %-*s  %s\n", width, helpCommand, "Print the usage of a command.")
+	_, _ = fmt.Fprintf(out, "\nRun '%s help <command>' for the arguments of a command.\n", s.flags.Name())
+}
+
+// exit handles the error according to the error handling of the flag set of the setup, as the flag package does.
+func (s *setup) exit(err error) error {
+	switch s.flags.ErrorHandling() {
+	case flag.ExitOnError:
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	case flag.PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	defined := false
+	fs.VisitAll(func(*flag.Flag) {
+		defined = true
+	})
+	return defined
+}
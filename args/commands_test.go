@@ -0,0 +1,131 @@
+package args_test
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type collectArgumentList struct {
+	sdk_args.DefaultArgumentList
+	Hostname string `default:"localhost" help:"Hostname to connect to."`
+}
+
+type discoverArgumentList struct {
+	Network string `default:"10.0.0.0/24" help:"Network to scan."`
+	Port    int    `default:"3306" help:"Port to probe."`
+}
+
+type commandsFixture struct {
+	collect  collectArgumentList
+	discover discoverArgumentList
+	commands []sdk_args.Command
+}
+
+func newCommandsFixture() *commandsFixture {
+	f := &commandsFixture{}
+	f.commands = []sdk_args.Command{
+		{Name: "collect", Help: "Collect metrics and inventory.", Args: &f.collect},
+		{Name: "discover", Help: "Discover the instances to monitor.", Args: &f.discover},
+		{Name: "version", Help: "Print the version."},
+	}
+	return f
+}
+
+func setupCommand(commands []sdk_args.Command, argv []string, out *bytes.Buffer) (*sdk_args.Command, error) {
+	fs := flag.NewFlagSet("nri-test", flag.ContinueOnError)
+	fs.SetOutput(out)
+	return sdk_args.SetupCommand(commands,
+		sdk_args.FlagSet(fs), sdk_args.Argv(argv), sdk_args.Environ([]string{"PORT=1234"}))
+}
+
+func TestSetupCommand(t *testing.T) {
+	var out bytes.Buffer
+
+	f := newCommandsFixture()
+	cmd, err := setupCommand(f.commands, []string{"discover", "-network=192.168.0.0/16"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "discover", cmd.Name)
+	assert.Equal(t, discoverArgumentList{Network: "192.168.0.0/16", Port: 1234}, f.discover)
+	assert.Equal(t, collectArgumentList{}, f.collect, "arguments of other commands are not set up")
+
+	f = newCommandsFixture()
+	cmd, err = setupCommand(f.commands, []string{"-hostname=db", "-metrics"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "collect", cmd.Name, "first command is the default")
+	assert.Equal(t, "db", f.collect.Hostname)
+	assert.True(t, f.collect.Metrics)
+
+	f = newCommandsFixture()
+	cmd, err = setupCommand(f.commands, []string{"version"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "version", cmd.Name)
+
+	_, err = setupCommand(f.commands, []string{"version", "-hostname=db"}, &out)
+	assert.Error(t, err, "commands don't accept the arguments of other commands")
+}
+
+func TestSetupCommandHelp(t *testing.T) {
+	var out bytes.Buffer
+	_, err := setupCommand(newCommandsFixture().commands, []string{"help"}, &out)
+	assert.Equal(t, flag.ErrHelp, err)
+	assert.Equal(t, `Usage: nri-test <command> [arguments]
+
+Commands:
+  collect   Collect metrics and inventory. (default)
+  discover  Discover the instances to monitor.
+  version   Print the version.
+  help      Print the usage of a command.
+
+Run 'nri-test help <command>' for the arguments of a command.
+`, out.String())
+
+	out.Reset()
+	_, err = setupCommand(newCommandsFixture().commands, []string{"help", "discover"}, &out)
+	assert.Equal(t, flag.ErrHelp, err)
+	expected := `Usage: nri-test discover [arguments]
+
+Discover the instances to monitor.
+
+Arguments:
+  -network string
+    	Network to scan. (default "10.0.0.0/24")
+  -port int
+    	Port to probe. (default 3306)
+`
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	_, err = setupCommand(newCommandsFixture().commands, []string{"discover", "-help"}, &out)
+	assert.Equal(t, flag.ErrHelp, err)
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	_, err = setupCommand(newCommandsFixture().commands, []string{"help", "version"}, &out)
+	assert.Equal(t, flag.ErrHelp, err)
+	assert.Equal(t, "Usage: nri-test version [arguments]\n\nPrint the version.\n", out.String())
+}
+
+func TestSetupCommandErrors(t *testing.T) {
+	var out bytes.Buffer
+	_, err := setupCommand(newCommandsFixture().commands, []string{"unknown"}, &out)
+	assert.EqualError(t, err, `unknown command "unknown"`)
+	assert.Contains(t, out.String(), "Commands:")
+
+	for name, commands := range map[string][]sdk_args.Command{
+		"no commands":     nil,
+		"empty name":      {{Name: ""}},
+		"help":            {{Name: "help"}},
+		"flag name":       {{Name: "-collect"}},
+		"non-struct args": {{Name: "collect", Args: new(int)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := setupCommand(commands, nil, &out)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package args
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configPathFlag is the name of the argument holding the path of the configuration file.
+const configPathFlag = "config_path"
+
+// loadConfigFile sets the flags not set yet from the values of a YAML or JSON configuration file, whose keys are
+// the argument names in underscore format. The arguments of nested structs can be set either by their prefixed
+// names or nested under the name of the struct. Files are parsed as JSON if they have the .json extension, and as
+// YAML otherwise.
+func loadConfigFile(fs *flag.FlagSet, path string, sources map[string]Source) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read config file: %s", err)
+	}
+
+	values := map[string]interface{}{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &values)
+	} else {
+		err = yaml.Unmarshal(content, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("can't parse config file %s: %s", path, err)
+	}
+
+	return setFromConfig(fs, path, "", values, sources)
+}
+
+// setFromConfig sets the flags not set yet named after the keys of values, prefixed.
+func setFromConfig(fs *flag.FlagSet, path, prefix string, values map[string]interface{}, sources map[string]Source) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		name := prefix + key
+		f := fs.Lookup(name)
+		if f == nil {
+			if nested, ok := jsonCompatible(values[key]).(map[string]interface{}); ok {
+				if err := setFromConfig(fs, path, name+"_", nested, sources); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("unknown argument %s in config file %s", name, path)
+		}
+		if _, ok := sources[name]; ok || name == configPathFlag {
+			continue
+		}
+
+		value, err := configValue(f, values[key])
+		if err != nil {
+			return fmt.Errorf("can't parse %s from config file: %s", name, err)
+		}
+		if err = f.Value.Set(value); err != nil {
+			return fmt.Errorf("can't parse %s from config file: %s", name, err)
+		}
+		sources[name] = SourceFile
+	}
+	return nil
+}
+
+// configValue formats a value of the configuration file as the flag would be set from the command line.
+// JSON arguments accept any value, list arguments accept lists and map arguments accept maps, while the rest only
+// accept scalar values.
+func configValue(f *flag.Flag, value interface{}) (string, error) {
+	switch f.Value.(type) {
+	case *JSON, *typedJSON:
+		out, err := json.Marshal(jsonCompatible(value))
+		return string(out), err
+	case *stringSlice:
+		if list, ok := value.([]interface{}); ok {
+			values := make([]string, 0, len(list))
+			for _, e := range list {
+				v, err := scalarConfigValue(e)
+				if err != nil {
+					return "", err
+				}
+				values = append(values, v)
+			}
+			return strings.Join(values, ","), nil
+		}
+	case *stringMap:
+		if m, ok := jsonCompatible(value).(map[string]interface{}); ok {
+			pairs := make([]string, 0, len(m))
+			for k, e := range m {
+				v, err := scalarConfigValue(e)
+				if err != nil {
+					return "", err
+				}
+				pairs = append(pairs, k+"="+v)
+			}
+			return strings.Join(pairs, ","), nil
+		}
+	}
+	return scalarConfigValue(value)
+}
+
+func scalarConfigValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int64, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// jsonCompatible converts the maps decoded from YAML, which may have non-string keys, into maps that can
+// be marshaled into JSON.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = jsonCompatible(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
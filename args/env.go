@@ -0,0 +1,77 @@
+package args
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// nonEnvChars matches the characters not allowed in environment variable names.
+var nonEnvChars = regexp.MustCompile("[^A-Z0-9_]+")
+
+// EnvPrefix reads the arguments from the environment variables with the given prefix, e.g. the integration
+// name, so they don't collide with the arguments of other integrations. The prefix is upper-cased and its
+// characters not allowed in environment variable names are replaced by underscores, so the password argument
+// is read from NRI_MYSQL_PASSWORD for the "nri-mysql" prefix.
+// The environment variables without prefix are ignored unless LegacyEnv is set.
+func EnvPrefix(prefix string) SetupOption {
+	return func(s *setup) {
+		s.envPrefix = envName(prefix)
+		if s.envPrefix != "" && !strings.HasSuffix(s.envPrefix, "_") {
+			s.envPrefix += "_"
+		}
+	}
+}
+
+// LegacyEnv reads the arguments not set from the prefixed environment variables (see EnvPrefix) from the
+// environment variables with their upper-cased name, e.g. PASSWORD for the password argument.
+func LegacyEnv() SetupOption {
+	return func(s *setup) {
+		s.legacyEnv = true
+	}
+}
+
+// envName returns the environment variable name for the given argument name.
+func envName(name string) string {
+	return nonEnvChars.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// envTags returns the environment variable names set through the `env:"NAME"` tag, by argument name.
+func envTags(args interface{}) map[string]string {
+	var arguments []argField
+	collectArgs(reflect.ValueOf(args).Elem(), "", &arguments)
+
+	tags := map[string]string{}
+	for _, a := range arguments {
+		if name := a.tag.Get("env"); name != "" {
+			tags[a.name] = name
+		}
+	}
+	return tags
+}
+
+// envNames returns the environment variables the argument is read from, in order of precedence.
+// An `env:"NAME"` tag sets the only environment variable, regardless of the prefix.
+func (s *setup) envNames(name string) []string {
+	if tagged, ok := s.envTags[name]; ok {
+		return []string{tagged}
+	}
+	if s.envPrefix == "" {
+		return []string{envName(name)}
+	}
+	names := []string{s.envPrefix + envName(name)}
+	if s.legacyEnv {
+		names = append(names, envName(name))
+	}
+	return names
+}
+
+// lookupEnv returns the value of the first environment variable the argument is read from that is set.
+func (s *setup) lookupEnv(name, suffix string) string {
+	for _, n := range s.envNames(name) {
+		if v := s.getenv(n + suffix); v != "" {
+			return v
+		}
+	}
+	return ""
+}
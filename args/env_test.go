@@ -0,0 +1,68 @@
+package args_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type envArgumentList struct {
+	Hostname string `default:"localhost" help:""`
+	Port     int    `default:"3306" help:""`
+	Username string `env:"MYSQL_USER" help:""`
+	Password string `help:""`
+}
+
+func setupEnvArgs(t *testing.T, environ []string, opts ...sdk_args.SetupOption) envArgumentList {
+	var args envArgumentList
+	opts = append([]sdk_args.SetupOption{
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+		sdk_args.Argv(nil),
+		sdk_args.Environ(environ),
+	}, opts...)
+	require.NoError(t, sdk_args.SetupArgs(&args, opts...))
+	return args
+}
+
+func TestSetupArgsEnvPrefix(t *testing.T) {
+	environ := []string{"NRI_MYSQL_HOSTNAME=prefixed", "HOSTNAME=legacy", "PORT=1234", "MYSQL_USER=admin", "USERNAME=other"}
+
+	args := setupEnvArgs(t, environ, sdk_args.EnvPrefix("nri-mysql"))
+	assert.Equal(t, envArgumentList{Hostname: "prefixed", Port: 3306, Username: "admin"}, args)
+
+	args = setupEnvArgs(t, environ, sdk_args.EnvPrefix("NRI_MYSQL_"), sdk_args.LegacyEnv())
+	assert.Equal(t, envArgumentList{Hostname: "prefixed", Port: 1234, Username: "admin"}, args)
+
+	args = setupEnvArgs(t, environ)
+	assert.Equal(t, envArgumentList{Hostname: "legacy", Port: 1234, Username: "admin"}, args)
+}
+
+func TestSetupArgsEnvPrefixFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "env")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	passwordFile := writeSecret(t, dir, "password", "pr3f1x3d")
+
+	args := setupEnvArgs(t, []string{"NRI_MYSQL_PASSWORD_FILE=" + passwordFile}, sdk_args.EnvPrefix("nri-mysql"))
+	assert.Equal(t, "pr3f1x3d", args.Password)
+
+	args = setupEnvArgs(t, []string{"PASSWORD_FILE=" + passwordFile}, sdk_args.EnvPrefix("nri-mysql"))
+	assert.Empty(t, args.Password)
+}
+
+func TestDescribeEnvPrefix(t *testing.T) {
+	arguments, err := sdk_args.Describe(&envArgumentList{}, sdk_args.EnvPrefix("nri-mysql"))
+	require.NoError(t, err)
+
+	var names []string
+	for _, a := range arguments {
+		names = append(names, a.EnvName)
+	}
+	assert.Equal(t, []string{"NRI_MYSQL_HOSTNAME", "NRI_MYSQL_PORT", "MYSQL_USER", "NRI_MYSQL_PASSWORD"}, names)
+}
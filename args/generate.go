@@ -0,0 +1,178 @@
+package args
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/internal/markdown"
+)
+
+// Argument describes an argument defined by an arguments struct.
+type Argument struct {
+	// Name in command-line format.
+	Name string
+	// EnvName is the environment variable the argument is read from.
+	EnvName string
+	// Type of the struct field.
+	Type     string
+	Default  string
+	Help     string
+	Required bool
+	Secret   bool
+}
+
+// Describe returns the arguments defined by the struct pointed by args, in definition order. An error is returned
+// if the struct can't be set up as arguments, e.g. because of an unsupported type or an invalid default value.
+// The given struct is not modified. Options changing the environment variables (see EnvPrefix) are applied
+// to the EnvName of the arguments.
+func Describe(args interface{}, opts ...SetupOption) ([]Argument, error) {
+	val := reflect.ValueOf(args)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("arguments must be a pointer to a struct")
+	}
+
+	// define the flags on a new instance, so the defaults are checked without modifying the given struct
+	instance := reflect.New(val.Elem().Type())
+	if err := defineFlags(flag.NewFlagSet("describe", flag.ContinueOnError), instance.Interface()); err != nil {
+		return nil, err
+	}
+
+	var fields []argField
+	collectArgs(instance.Elem(), "", &fields)
+	s := setup{}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	s.envTags = envTags(instance.Interface())
+
+	arguments := make([]Argument, 0, len(fields))
+	for _, f := range fields {
+		arguments = append(arguments, Argument{
+			Name:     f.name,
+			EnvName:  s.envNames(f.name)[0],
+			Type:     f.value.Type().String(),
+			Default:  f.tag.Get("default"),
+			Help:     f.tag.Get("help"),
+			Required: f.tag.Get("required") == "true",
+			Secret:   f.tag.Get("secret") == "true",
+		})
+	}
+	return arguments, nil
+}
+
+// SampleConfig returns a sample agent configuration file for the integration, with an instance running the
+// "metrics" command of the definition file returned by SampleDefinition. Every argument is listed with its help
+// as a comment, named after its environment variable, and all but the required ones are commented out, as their
+// defaults apply anyway.
+func SampleConfig(integrationName string, args interface{}, opts ...SetupOption) ([]byte, error) {
+	arguments, err := Describe(args, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("integration_name: %s\n\n", integrationName))
+	buf.WriteString("instances:\n")
+	buf.WriteString(fmt.Sprintf("  - name: %s-%s\n", integrationName, defaultCommand))
+	buf.WriteString(fmt.Sprintf("    command: %s\n", defaultCommand))
+	buf.WriteString("    arguments:\n")
+	for n, a := range arguments {
+		if n > 0 {
+			buf.WriteString("\n")
+		}
+		for _, line := range strings.Split(describeHelp(a), "\n") {
+			buf.WriteString(strings.TrimRight("      # "+line, " ") + "\n")
+		}
+
+		value, err := yaml.Marshal(a.Default)
+		if err != nil {
+			return nil, err
+		}
+		prefix := "      # "
+		if a.Required {
+			prefix = "      "
+		}
+		// the agent passes the arguments to the integration as upper-cased environment variables
+		buf.WriteString(fmt.Sprintf("%s%s: %s", prefix, strings.ToLower(a.EnvName), value))
+	}
+	return buf.Bytes(), nil
+}
+
+// defaultCommand is the agent command running integrations without commands, see SampleDefinition.
+const defaultCommand = "metrics"
+
+type definitionFile struct {
+	Name            string        `yaml:"name"`
+	Description     string        `yaml:"description"`
+	ProtocolVersion int           `yaml:"protocol_version"`
+	OS              string        `yaml:"os"`
+	Commands        yaml.MapSlice `yaml:"commands"`
+}
+
+type definitionCommand struct {
+	Command  []string `yaml:"command"`
+	Interval int      `yaml:"interval"`
+}
+
+// SampleDefinition returns a sample agent definition file for the integration, run from the given binary path.
+// Every given command (see SetupCommand) is defined as an agent command passing its name as first argument to the
+// binary. Without commands, a single "metrics" command runs the binary without arguments.
+func SampleDefinition(integrationName, description, binary string, commands ...string) ([]byte, error) {
+	definition := definitionFile{
+		Name:            integrationName,
+		Description:     description,
+		ProtocolVersion: 4,
+		OS:              "linux",
+	}
+	if len(commands) == 0 {
+		definition.Commands = yaml.MapSlice{{Key: defaultCommand, Value: definitionCommand{
+			Command:  []string{binary},
+			Interval: 15,
+		}}}
+	}
+	for _, c := range commands {
+		definition.Commands = append(definition.Commands, yaml.MapItem{Key: c, Value: definitionCommand{
+			Command:  []string{binary, c},
+			Interval: 15,
+		}})
+	}
+	return yaml.Marshal(definition)
+}
+
+// Markdown returns a Markdown table describing the arguments defined by the struct pointed by args.
+func Markdown(args interface{}, opts ...SetupOption) (string, error) {
+	arguments, err := Describe(args, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Argument | Environment variable | Type | Default | Description |\n")
+	sb.WriteString("|----------|----------------------|------|---------|-------------|\n")
+	for _, a := range arguments {
+		def := ""
+		if a.Default != "" {
+			def = "`" + markdown.EscapeCell(a.Default) + "`"
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | `%s` | `%s` | %s | %s |\n",
+			a.Name, a.EnvName, a.Type, def, markdown.EscapeCell(describeHelp(a))))
+	}
+	return sb.String(), nil
+}
+
+// describeHelp returns the help of the argument noting whether it's required or secret.
+func describeHelp(a Argument) string {
+	help := a.Help
+	if a.Required {
+		help = strings.TrimSpace(help + " Required.")
+	}
+	if a.Secret {
+		help = strings.TrimSpace(help + " Secret.")
+	}
+	return help
+}
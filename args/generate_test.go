@@ -0,0 +1,117 @@
+package args_test
+
+import (
+	"testing"
+
+	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type generatedArgumentList struct {
+	Hostname string `default:"localhost" help:"Hostname or IP where MySQL is running."`
+	Port     int    `default:"3306" help:"Port on which MySQL server is listening."`
+	Password string `required:"true" secret:"true" help:"Password for the given user."`
+	Database struct {
+		Tables []string `help:"Tables to monitor | comma-separated."`
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	args := generatedArgumentList{}
+	arguments, err := sdk_args.Describe(&args)
+	require.NoError(t, err)
+
+	assert.Equal(t, []sdk_args.Argument{
+		{Name: "hostname", EnvName: "HOSTNAME", Type: "string", Default: "localhost", Help: "Hostname or IP where MySQL is running."},
+		{Name: "port", EnvName: "PORT", Type: "int", Default: "3306", Help: "Port on which MySQL server is listening."},
+		{Name: "password", EnvName: "PASSWORD", Type: "string", Help: "Password for the given user.", Required: true, Secret: true},
+		{Name: "database_tables", EnvName: "DATABASE_TABLES", Type: "[]string", Help: "Tables to monitor | comma-separated."},
+	}, arguments)
+	assert.Equal(t, generatedArgumentList{}, args, "described struct is not modified")
+
+	_, err = sdk_args.Describe(&struct {
+		Port int `default:"notanint"`
+	}{})
+	assert.Error(t, err)
+	_, err = sdk_args.Describe(generatedArgumentList{})
+	assert.Error(t, err)
+}
+
+func TestSampleConfig(t *testing.T) {
+	config, err := sdk_args.SampleConfig("nri-mysql", &generatedArgumentList{})
+	require.NoError(t, err)
+
+	assert.Equal(t, `integration_name: nri-mysql
+
+instances:
+  - name: nri-mysql-metrics
+    command: metrics
+    arguments:
+      # Hostname or IP where MySQL is running.
+      # hostname: localhost
+
+      # Port on which MySQL server is listening.
+      # port: "3306"
+
+      # Password for the given user. Required. Secret.
+      password: ""
+
+      # Tables to monitor | comma-separated.
+      # database_tables: ""
+`, string(config))
+}
+
+func TestSampleDefinition(t *testing.T) {
+	definition, err := sdk_args.SampleDefinition("nri-mysql", "Reports MySQL metrics", "./bin/nri-mysql")
+	require.NoError(t, err)
+
+	assert.Equal(t, `name: nri-mysql
+description: Reports MySQL metrics
+protocol_version: 4
+os: linux
+commands:
+  metrics:
+    command:
+    - ./bin/nri-mysql
+    interval: 15
+`, string(definition))
+
+	definition, err = sdk_args.SampleDefinition("nri-mysql", "Reports MySQL metrics", "./bin/nri-mysql", "collect", "inventory")
+	require.NoError(t, err)
+
+	assert.Contains(t, string(definition), `commands:
+  collect:
+    command:
+    - ./bin/nri-mysql
+    - collect
+    interval: 15
+  inventory:
+    command:
+    - ./bin/nri-mysql
+    - inventory
+    interval: 15
+`)
+}
+
+func TestMarkdown(t *testing.T) {
+	reference, err := sdk_args.Markdown(&generatedArgumentList{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "| Argument | Environment variable | Type | Default | Description |\n"+
+		"|----------|----------------------|------|---------|-------------|\n"+
+		"| `hostname` | `HOSTNAME` | `string` | `localhost` | Hostname or IP where MySQL is running. |\n"+
+		"| `port` | `PORT` | `int` | `3306` | Port on which MySQL server is listening. |\n"+
+		"| `password` | `PASSWORD` | `string` |  | Password for the given user. Required. Secret. |\n"+
+		"| `database_tables` | `DATABASE_TABLES` | `[]string` |  | Tables to monitor \\| comma-separated. |\n",
+		reference)
+}
+
+func TestMarkdownEscapesDefaults(t *testing.T) {
+	reference, err := sdk_args.Markdown(&struct {
+		Pattern string `default:"^(users|orders)$" help:"Tables to monitor."`
+	}{})
+	require.NoError(t, err)
+
+	assert.Contains(t, reference, "| `pattern` | `PATTERN` | `string` | `^(users\\|orders)$` | Tables to monitor. |\n")
+}
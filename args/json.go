@@ -1,9 +1,11 @@
 package args
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"reflect"
 )
 
 // JSON type, to be used from the arguments structs.
@@ -25,6 +27,15 @@ func (i *JSON) Set(s string) error {
 	return nil
 }
 
+// Decode decodes the hold value into target, which must be a pointer, failing on fields unknown to target.
+func (i *JSON) Decode(target interface{}) error {
+	s, err := json.Marshal(i.value)
+	if err != nil {
+		return fmt.Errorf("bad JSON, %v", err)
+	}
+	return decodeStrict(s, target)
+}
+
 // Get returns the hold value
 func (i *JSON) Get() interface{} { return i.value }
 
@@ -34,6 +45,87 @@ func (i *JSON) String() string {
 	return string(s)
 }
 
-func jsonVar(p *JSON, name string, usage string) {
-	flag.CommandLine.Var(p, name, usage)
+func jsonVar(fs *flag.FlagSet, p *JSON, name string, usage string) {
+	fs.Var(p, name, usage)
+}
+
+// typedJSON is a flag.Value decoding JSON into an argument of any type, for the fields with the
+// `format:"json"` tag.
+type typedJSON struct {
+	target reflect.Value
+}
+
+func newTypedJSON(target reflect.Value) *typedJSON {
+	return &typedJSON{target: target}
+}
+
+// Set decodes the given JSON into the argument, failing on unknown fields. If the argument type, or the
+// type of its elements for lists and maps, has a Validate() error method, it's called on the decoded values.
+func (j *typedJSON) Set(s string) error {
+	v := reflect.New(j.target.Type())
+	if err := decodeStrict([]byte(s), v.Interface()); err != nil {
+		return err
+	}
+	if err := validateDecoded(v); err != nil {
+		return err
+	}
+	j.target.Set(v.Elem())
+	return nil
+}
+
+// String converts to string
+func (j *typedJSON) String() string {
+	if !j.target.IsValid() {
+		return ""
+	}
+	s, _ := json.Marshal(j.target.Interface())
+	return string(s)
+}
+
+// validateDecoded calls the Validate() error method of the decoded value, or of its elements for lists and maps,
+// including pointer elements.
+func validateDecoded(v reflect.Value) error {
+	if v.Kind() != reflect.Ptr {
+		// work with a pointer, so Validate methods with pointer receivers are found too
+		if v.CanAddr() {
+			v = v.Addr()
+		} else {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			v = p
+		}
+	}
+	if v.IsNil() {
+		return nil
+	}
+	if validator, ok := v.Interface().(interface{ Validate() error }); ok {
+		return validator.Validate()
+	}
+	v = v.Elem()
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateDecoded(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateDecoded(iter.Value()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeStrict(s []byte, target interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(target); err != nil {
+		return fmt.Errorf("bad JSON, %v", err)
+	}
+	return nil
 }
@@ -0,0 +1,228 @@
+package args
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces the secret values in messages and configuration dumps.
+const redacted = "******"
+
+// envFileSuffix is the suffix of the environment variables pointing to a file holding the value of an argument.
+const envFileSuffix = "_FILE"
+
+// secretRefRE matches secret references in argument values, e.g. ${env:DB_PASSWORD}.
+var secretRefRE = regexp.MustCompile(`\$\{(env|file|cmd):([^}]*)\}`)
+
+// secrets holds the values resolved from secret references or files in the process.
+var secrets = struct {
+	sync.Mutex
+	values map[string]bool
+}{values: map[string]bool{}}
+
+// SecretCommands enables the ${cmd:command args} secret references, which are replaced by the output of the
+// command. The command is run directly, without a shell.
+func SecretCommands() SetupOption {
+	return func(s *setup) {
+		s.secretCommands = true
+	}
+}
+
+// SecretValues returns the values of the arguments resolved from secret references or files in the process,
+// so they can be redacted.
+func SecretValues() []string {
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	values := make([]string, 0, len(secrets.values))
+	for v := range secrets.values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (s *setup) markSecret(name, value string) {
+	s.secretArgs[name] = true
+	if value == "" {
+		return
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values[value] = true
+}
+
+// markTaggedSecrets marks the arguments with the `secret:"true"` tag as secrets. Only string arguments, or lists
+// and maps of strings, can be tagged as secrets, and an error is returned otherwise.
+func (s *setup) markTaggedSecrets(args interface{}) error {
+	var arguments []argField
+	collectArgs(reflect.ValueOf(args).Elem(), "", &arguments)
+
+	for _, a := range arguments {
+		if a.tag.Get("secret") != "true" {
+			continue
+		}
+		switch {
+		case a.value.Kind() == reflect.String:
+			s.markSecret(a.name, a.value.String())
+		case (a.value.Kind() == reflect.Slice || a.value.Kind() == reflect.Array) &&
+			a.value.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < a.value.Len(); i++ {
+				s.markSecret(a.name, a.value.Index(i).String())
+			}
+		case a.value.Kind() == reflect.Map && a.value.Type().Elem().Kind() == reflect.String:
+			for _, k := range a.value.MapKeys() {
+				s.markSecret(a.name, a.value.MapIndex(k).String())
+			}
+		default:
+			return fmt.Errorf("can't mark %s as secret: only strings, lists and maps of strings can be secrets", a.name)
+		}
+	}
+	return nil
+}
+
+// Redact replaces the secret values known in the process (see SecretValues) in the message, so it can be
+// logged safely. Log implementations can use it to redact every message.
+func Redact(message string) string {
+	values := SecretValues()
+	// replace longer values first, in case a secret contains another one
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, v := range values {
+		message = strings.Replace(message, v, redacted, -1)
+	}
+	return message
+}
+
+// getArgsFromEnvFiles sets the flags not set yet from the files pointed by their environment variables with the
+// _FILE suffix, e.g. PASSWORD_FILE for the password argument.
+func (s *setup) getArgsFromEnvFiles(sources map[string]Source) error {
+	var err error
+	s.flags.VisitAll(func(f *flag.Flag) {
+		if _, ok := sources[f.Name]; ok || err != nil {
+			return
+		}
+		path := s.lookupEnv(f.Name, envFileSuffix)
+		if path == "" {
+			return
+		}
+
+		var value string
+		if value, err = readSecretFile(path); err != nil {
+			err = fmt.Errorf("can't read %s: %s", f.Name, err)
+			return
+		}
+		if err = f.Value.Set(value); err != nil {
+			err = fmt.Errorf("can't parse %s: %s", f.Name, err)
+			return
+		}
+		sources[f.Name] = SourceEnv
+		s.markSecret(f.Name, value)
+	})
+	return err
+}
+
+// resolveSecrets replaces the secret references (see SetupArgs) in the values of string, list and map arguments.
+func (s *setup) resolveSecrets(args interface{}) error {
+	var arguments []argField
+	collectArgs(reflect.ValueOf(args).Elem(), "", &arguments)
+
+	for _, a := range arguments {
+		switch a.value.Kind() {
+		case reflect.String:
+			if err := s.resolveValue(a.name, a.value); err != nil {
+				return err
+			}
+		case reflect.Slice:
+			if a.value.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			for i := 0; i < a.value.Len(); i++ {
+				if err := s.resolveValue(a.name, a.value.Index(i)); err != nil {
+					return err
+				}
+			}
+		case reflect.Map:
+			if a.value.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			for _, k := range a.value.MapKeys() {
+				v := reflect.New(a.value.Type().Elem()).Elem()
+				v.Set(a.value.MapIndex(k))
+				if err := s.resolveValue(a.name, v); err != nil {
+					return err
+				}
+				a.value.SetMapIndex(k, v)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveValue replaces the secret references of a settable string value, marking the argument as secret.
+func (s *setup) resolveValue(name string, value reflect.Value) error {
+	if !secretRefRE.MatchString(value.String()) {
+		return nil
+	}
+
+	var err error
+	resolved := secretRefRE.ReplaceAllStringFunc(value.String(), func(ref string) string {
+		m := secretRefRE.FindStringSubmatch(ref)
+		v, rerr := s.resolveRef(m[1], m[2])
+		if rerr != nil && err == nil {
+			err = fmt.Errorf("can't resolve %s of %s: %s", ref, name, rerr)
+		}
+		return v
+	})
+	if err != nil {
+		return err
+	}
+
+	value.SetString(resolved)
+	s.markSecret(name, resolved)
+	return nil
+}
+
+func (s *setup) resolveRef(provider, ref string) (string, error) {
+	switch provider {
+	case "env":
+		v := s.getenv(ref)
+		if v == "" {
+			return "", errors.New("environment variable is not set")
+		}
+		return v, nil
+	case "file":
+		return readSecretFile(ref)
+	default:
+		if !s.secretCommands {
+			return "", errors.New("secret commands are not enabled")
+		}
+		fields := strings.Fields(ref)
+		if len(fields) == 0 {
+			return "", errors.New("empty command")
+		}
+		out, err := exec.Command(fields[0], fields[1:]...).Output()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	}
+}
+
+func readSecretFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,127 @@
+package args_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type secretArgumentList struct {
+	Username  string            `default:"root" help:""`
+	Password  string            `required:"true" help:""`
+	Token     string            `help:""`
+	DSN       string            `help:""`
+	Databases []string          `help:""`
+	Headers   map[string]string `help:""`
+}
+
+func writeSecret(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestSetupArgsSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	passwordFile := writeSecret(t, dir, "password", "s3cr3t\n")
+	tokenFile := writeSecret(t, dir, "token", "t0k3n")
+
+	var args secretArgumentList
+	err = sdk_args.SetupArgs(&args,
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+		sdk_args.Argv([]string{
+			"-token=${file:" + tokenFile + "}",
+			"-dsn=user:${env:DB_PASSWORD}@tcp(localhost)",
+			"-databases=${env:DB_NAME},other",
+			"-headers=Authorization=Bearer ${file:" + tokenFile + "}",
+		}),
+		sdk_args.Environ([]string{"PASSWORD_FILE=" + passwordFile, "DB_PASSWORD=dbpass", "DB_NAME=inventorydb"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "root", args.Username)
+	assert.Equal(t, "s3cr3t", args.Password)
+	assert.Equal(t, "t0k3n", args.Token)
+	assert.Equal(t, "user:dbpass@tcp(localhost)", args.DSN)
+	assert.Equal(t, []string{"inventorydb", "other"}, args.Databases)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer t0k3n"}, args.Headers)
+
+	values := sdk_args.SecretValues()
+	assert.Contains(t, values, "s3cr3t")
+	assert.Contains(t, values, "t0k3n")
+	assert.Contains(t, values, "user:dbpass@tcp(localhost)")
+	assert.NotContains(t, values, "root")
+}
+
+func TestSetupArgsSecretsErrors(t *testing.T) {
+	setup := func(argv []string, environ []string, opts ...sdk_args.SetupOption) error {
+		var args secretArgumentList
+		opts = append(opts,
+			sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+			sdk_args.Argv(append([]string{"-password=pass"}, argv...)),
+			sdk_args.Environ(environ))
+		return sdk_args.SetupArgs(&args, opts...)
+	}
+
+	assert.Error(t, setup([]string{"-token=${env:MISSING}"}, nil))
+	assert.Error(t, setup([]string{"-token=${file:/missing/token}"}, nil))
+	assert.Error(t, setup(nil, []string{"TOKEN_FILE=/missing/token"}))
+	err := setup([]string{"-token=${cmd:echo t0k3n}"}, nil)
+	assert.EqualError(t, err, "can't resolve ${cmd:echo t0k3n} of token: secret commands are not enabled")
+}
+
+func TestSetupArgsSecretCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("echo is not a command in Windows")
+	}
+
+	var args secretArgumentList
+	err := sdk_args.SetupArgs(&args,
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+		sdk_args.Argv([]string{"-password=${cmd:echo cmdpass}"}),
+		sdk_args.Environ(nil),
+		sdk_args.SecretCommands())
+	require.NoError(t, err)
+
+	assert.Equal(t, "cmdpass", args.Password)
+	assert.Contains(t, sdk_args.SecretValues(), "cmdpass")
+}
+
+func TestSetupArgsSecretsAreResolvedBeforeValidation(t *testing.T) {
+	var args secretArgumentList
+	err := sdk_args.SetupArgs(&args,
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+		sdk_args.Argv(nil),
+		sdk_args.Environ([]string{"PASSWORD=${env:EMPTY}", "EMPTY="}))
+	assert.Error(t, err)
+}
+
+func TestSetupArgsTaggedSecrets(t *testing.T) {
+	var args struct {
+		APIKey  string            `secret:"true" help:""`
+		Keys    []string          `secret:"true" help:""`
+		Headers map[string]string `secret:"true" help:""`
+	}
+	require.NoError(t, sdk_args.SetupArgs(&args,
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+		sdk_args.Argv([]string{"-api_key=t4gg3d-k3y", "-keys=t4gg3d-l1st", "-headers=X-Token=t4gg3d-m4p"})))
+
+	assert.Equal(t, "****** ****** X-Token: ******", sdk_args.Redact("t4gg3d-k3y t4gg3d-l1st X-Token: t4gg3d-m4p"))
+
+	var invalid struct {
+		Verbose bool `default:"false" secret:"true" help:""`
+	}
+	err := sdk_args.SetupArgs(&invalid,
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)), sdk_args.Argv([]string{"-verbose"}))
+	assert.EqualError(t, err, "can't mark verbose as secret: only strings, lists and maps of strings can be secrets")
+	assert.NotContains(t, sdk_args.SecretValues(), "true")
+}
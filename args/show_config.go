@@ -0,0 +1,41 @@
+package args
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// showConfigFlag is the name of the argument enabling the configuration dump.
+const showConfigFlag = "show_config"
+
+// ErrShowConfig is returned by SetupArgs after printing the effective arguments, when the ShowConfig argument
+// is set. As with the -help flag, the process exits instead if the flag set exits on errors, which is the case
+// of flag.CommandLine.
+var ErrShowConfig = errors.New("effective configuration shown")
+
+// showConfig prints the effective value of every argument along with its source into the output of the flag set,
+// redacting secrets.
+func (s *setup) showConfig(sources map[string]Source) error {
+	out := s.flags.Output()
+	s.flags.VisitAll(func(f *flag.Flag) {
+		source, ok := sources[f.Name]
+		if !ok {
+			source = SourceDefault
+		}
+
+		value := f.Value.String()
+		if s.secretArgs[f.Name] && value != "" {
+			value = redacted
+		} else {
+			value = Redact(value)
+		}
+		_, _ = fmt.Fprintf(out, "%s=%s (%s)\n", f.Name, value, source)
+	})
+
+	if s.flags.ErrorHandling() == flag.ExitOnError {
+		os.Exit(0)
+	}
+	return ErrShowConfig
+}
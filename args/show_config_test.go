@@ -0,0 +1,56 @@
+package args_test
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupArgsShowConfig(t *testing.T) {
+	var args struct {
+		sdk_args.DefaultArgumentList
+		Hostname string `default:"localhost" help:""`
+		Port     int    `required:"true" help:"" default:"0"`
+		Password string `secret:"true" help:""`
+		DSN      string `help:""`
+		Empty    string `secret:"true" help:""`
+	}
+
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	err := sdk_args.SetupArgs(&args,
+		sdk_args.FlagSet(fs),
+		sdk_args.Argv([]string{"-show_config", "-password=hunter22"}),
+		sdk_args.Environ([]string{"HOSTNAME=dbhost", "DSN=root:hunter22@dbhost"}))
+	require.Equal(t, sdk_args.ErrShowConfig, err, "arguments are not validated")
+
+	dump := out.String()
+	assert.Contains(t, dump, "hostname=dbhost (env)\n")
+	assert.Contains(t, dump, "port=0 (default)\n")
+	assert.Contains(t, dump, "password=****** (flag)\n")
+	assert.Contains(t, dump, "dsn=root:******@dbhost (env)\n")
+	assert.Contains(t, dump, "empty= (default)\n")
+	assert.Contains(t, dump, "show_config=true (flag)\n")
+	assert.NotContains(t, dump, "hunter22")
+}
+
+func TestRedact(t *testing.T) {
+	var args struct {
+		APIKey string `secret:"true" help:""`
+	}
+	err := sdk_args.SetupArgs(&args,
+		sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+		sdk_args.Argv([]string{"-api_key=abcd1234"}),
+		sdk_args.Environ(nil))
+	require.NoError(t, err)
+
+	assert.Contains(t, sdk_args.SecretValues(), "abcd1234")
+	assert.Equal(t, "request failed with key ****** and key ******",
+		sdk_args.Redact("request failed with key abcd1234 and key abcd1234"))
+	assert.Equal(t, "nothing to hide", sdk_args.Redact("nothing to hide"))
+}
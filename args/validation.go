@@ -0,0 +1,298 @@
+package args
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source is where the value of an argument was taken from.
+type Source string
+
+// Argument sources
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+	SourceDefault Source = "default"
+)
+
+// ArgumentError describes an argument whose value doesn't pass validation.
+type ArgumentError struct {
+	// Name of the argument in command-line format.
+	Name   string
+	Source Source
+	Reason string
+}
+
+// Error fulfills error interface.
+func (e ArgumentError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Name, e.Source, e.Reason)
+}
+
+// ValidationError lists every argument not passing validation.
+type ValidationError []ArgumentError
+
+// Error fulfills error interface.
+func (e ValidationError) Error() string {
+	reasons := make([]string, 0, len(e))
+	for _, ae := range e {
+		reasons = append(reasons, ae.Error())
+	}
+	return "invalid arguments: " + strings.Join(reasons, "; ")
+}
+
+// argField is an argument along with its struct field.
+type argField struct {
+	name  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// validate checks the arguments against the validation tags of their fields (see SetupArgs), returning a
+// ValidationError listing every invalid argument with the source of its value.
+// An error other than ValidationError is returned when any tag is malformed.
+func validate(args interface{}, sources map[string]Source) error {
+	var arguments []argField
+	collectArgs(reflect.ValueOf(args).Elem(), "", &arguments)
+
+	var errs ValidationError
+	groups := map[string][]string{}
+	for _, a := range arguments {
+		source, ok := sources[a.name]
+		if !ok {
+			source = SourceDefault
+		}
+
+		reasons, err := checkArg(a, source)
+		if err != nil {
+			return fmt.Errorf("invalid validation tags of %s: %s", a.name, err)
+		}
+		for _, r := range reasons {
+			errs = append(errs, ArgumentError{Name: a.name, Source: source, Reason: r})
+		}
+
+		if group := a.tag.Get("exclusive"); group != "" && !a.value.IsZero() {
+			groups[group] = append(groups[group], a.name)
+			if len(groups[group]) > 1 {
+				errs = append(errs, ArgumentError{
+					Name:   a.name,
+					Source: source,
+					Reason: fmt.Sprintf("can't be set along with %s", strings.Join(groups[group][:len(groups[group])-1], ", ")),
+				})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// collectArgs walks the argument struct as defineFlags does, collecting every argument.
+func collectArgs(val reflect.Value, prefix string, arguments *[]argField) {
+	for i := 0; i < val.NumField(); i++ {
+		valueField := val.Field(i)
+		typeField := val.Type().Field(i)
+		name := prefix + underscore(typeField.Name)
+
+		if isNestedArgs(valueField, typeField) {
+			nestedPrefix := name + "_"
+			if typeField.Anonymous {
+				nestedPrefix = prefix
+			}
+			collectArgs(valueField, nestedPrefix, arguments)
+			continue
+		}
+		*arguments = append(*arguments, argField{name: name, value: valueField, tag: typeField.Tag})
+	}
+}
+
+// isNestedArgs returns true if the field is a struct whose fields are arguments, rather than an argument itself.
+func isNestedArgs(field reflect.Value, typeField reflect.StructField) bool {
+	if !isArgsStruct(field) || typeField.Tag.Get("format") == "json" {
+		return false
+	}
+	switch field.Addr().Interface().(type) {
+	case *JSON, flag.Value:
+		return false
+	}
+	return true
+}
+
+// isArgsStruct returns true if the value is a struct that can hold arguments, that is, all its fields are
+// exported. Structs with unexported fields, like time.Time, aren't supported as arguments.
+func isArgsStruct(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct || !v.CanInterface() {
+		return false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// checkArg returns the reasons why the argument doesn't pass the validation tags. Required arguments are only
+// missing when they keep their zero default value, so zero values set explicitly, e.g. -port=0, are accepted.
+func checkArg(a argField, source Source) ([]string, error) {
+	if a.tag.Get("required") == "true" && source == SourceDefault && a.value.IsZero() {
+		return []string{"is required"}, nil
+	}
+
+	if a.value.IsZero() && isCollection(a.value) {
+		return nil, nil
+	}
+
+	var reasons []string
+	for _, bound := range []string{"min", "max"} {
+		limit, ok := a.tag.Lookup(bound)
+		if !ok {
+			continue
+		}
+		reason, err := checkBound(a.value, bound, limit)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	elements := []string{fmt.Sprint(a.value.Interface())}
+	if a.value.Kind() == reflect.Slice {
+		elements = elements[:0]
+		for i := 0; i < a.value.Len(); i++ {
+			elements = append(elements, fmt.Sprint(a.value.Index(i).Interface()))
+		}
+	}
+
+	if enum, ok := a.tag.Lookup("enum"); ok {
+		allowed := splitList(enum)
+		for _, e := range elements {
+			if !contains(allowed, e) {
+				reasons = append(reasons, fmt.Sprintf("%q is not one of %s", e, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	if expr, ok := a.tag.Lookup("regex"); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range elements {
+			if !re.MatchString(e) {
+				reasons = append(reasons, fmt.Sprintf("%q doesn't match %s", e, expr))
+			}
+		}
+	}
+
+	return reasons, nil
+}
+
+// checkBound returns the reason why the value is out of the min or max bound, or an empty string if it's not.
+func checkBound(value reflect.Value, bound, limit string) (string, error) {
+	var cmp int
+	var shown interface{} = value.Interface()
+
+	switch {
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		l, err := time.ParseDuration(limit)
+		if err != nil {
+			return "", err
+		}
+		cmp = compareInt(value.Int(), int64(l))
+	case isCollection(value):
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return "", err
+		}
+		cmp = compareInt(int64(value.Len()), int64(l))
+		shown = fmt.Sprintf("length %d", value.Len())
+	default:
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			l, err := strconv.ParseInt(limit, 10, 64)
+			if err != nil {
+				return "", err
+			}
+			cmp = compareInt(value.Int(), l)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			l, err := strconv.ParseUint(limit, 10, 64)
+			if err != nil {
+				return "", err
+			}
+			cmp = compareUint(value.Uint(), l)
+		case reflect.Float32, reflect.Float64:
+			l, err := strconv.ParseFloat(limit, 64)
+			if err != nil {
+				return "", err
+			}
+			cmp = compareFloat(value.Float(), l)
+		default:
+			return "", fmt.Errorf("%s is not supported for %s values", bound, value.Type())
+		}
+	}
+
+	if bound == "min" && cmp < 0 {
+		return fmt.Sprintf("%v is lower than the minimum %s", shown, limit), nil
+	}
+	if bound == "max" && cmp > 0 {
+		return fmt.Sprintf("%v is greater than the maximum %s", shown, limit), nil
+	}
+	return "", nil
+}
+
+func isCollection(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
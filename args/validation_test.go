@@ -0,0 +1,147 @@
+package args_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	sdk_args "github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validatedArgumentList struct {
+	Hostname  string        `required:"true" help:""`
+	Port      int           `default:"3306" min:"1" max:"65535" help:""`
+	Mode      string        `default:"fast" enum:"fast,safe" help:""`
+	Database  string        `regex:"[a-z_]+" help:""`
+	Tables    []string      `enum:"users,orders" max:"2" help:""`
+	Timeout   time.Duration `default:"10s" min:"1s" help:""`
+	Ratio     float64       `default:"0.5" min:"0" max:"1" help:""`
+	Password  string        `exclusive:"auth" help:""`
+	TokenFile string        `exclusive:"auth" help:""`
+}
+
+func TestSetupArgsValidation(t *testing.T) {
+	os.Args = []string{"cmd", "-hostname=localhost", "-database=my_db", "-tables=users,orders", "-password=secret"}
+
+	var args validatedArgumentList
+	clearFlagSet()
+	assert.NoError(t, sdk_args.SetupArgs(&args))
+}
+
+func TestSetupArgsInvalidEnvironmentValues(t *testing.T) {
+	_ = os.Setenv("TIMEOUT", "abc")
+	_ = os.Setenv("PORT", "http")
+	defer func() {
+		_ = os.Unsetenv("TIMEOUT")
+		_ = os.Unsetenv("PORT")
+	}()
+	os.Args = []string{"cmd", "-hostname=localhost"}
+
+	var args validatedArgumentList
+	clearFlagSet()
+	err := sdk_args.SetupArgs(&args)
+	require.Error(t, err)
+
+	verr, ok := err.(sdk_args.ValidationError)
+	require.True(t, ok)
+	assert.Equal(t, sdk_args.ValidationError{
+		{Name: "port", Source: sdk_args.SourceEnv, Reason: "invalid value, parse error"},
+		{Name: "timeout", Source: sdk_args.SourceEnv, Reason: "invalid value, parse error"},
+	}, verr)
+}
+
+func TestSetupArgsRequiredAcceptsExplicitZeroValues(t *testing.T) {
+	type argumentList struct {
+		Port    int  `default:"0" required:"true" help:""`
+		Enabled bool `default:"false" required:"true" help:""`
+	}
+	setup := func(argv, environ []string) error {
+		var args argumentList
+		return sdk_args.SetupArgs(&args,
+			sdk_args.FlagSet(flag.NewFlagSet("cmd", flag.ContinueOnError)),
+			sdk_args.Argv(argv), sdk_args.Environ(environ))
+	}
+
+	assert.NoError(t, setup([]string{"-port=0"}, []string{"ENABLED=false"}))
+	assert.Equal(t, sdk_args.ValidationError{
+		{Name: "port", Source: sdk_args.SourceDefault, Reason: "is required"},
+		{Name: "enabled", Source: sdk_args.SourceDefault, Reason: "is required"},
+	}, setup(nil, nil))
+}
+
+func TestSetupArgsValidationErrors(t *testing.T) {
+	_ = os.Setenv("MODE", "unsafe")
+	defer func() { _ = os.Unsetenv("MODE") }()
+	os.Args = []string{
+		"cmd",
+		"-port=70000",
+		"-database=my-db",
+		"-tables=users,orders,items",
+		"-timeout=10ms",
+		"-ratio=2",
+		"-password=secret",
+		"-token_file=/tmp/token",
+	}
+
+	var args validatedArgumentList
+	clearFlagSet()
+	err := sdk_args.SetupArgs(&args)
+	require.Error(t, err)
+
+	verr, ok := err.(sdk_args.ValidationError)
+	require.True(t, ok)
+	assert.Equal(t, sdk_args.ValidationError{
+		{Name: "hostname", Source: sdk_args.SourceDefault, Reason: "is required"},
+		{Name: "port", Source: sdk_args.SourceFlag, Reason: "70000 is greater than the maximum 65535"},
+		{Name: "mode", Source: sdk_args.SourceEnv, Reason: `"unsafe" is not one of fast, safe`},
+		{Name: "database", Source: sdk_args.SourceFlag, Reason: `"my-db" doesn't match [a-z_]+`},
+		{Name: "tables", Source: sdk_args.SourceFlag, Reason: "length 3 is greater than the maximum 2"},
+		{Name: "tables", Source: sdk_args.SourceFlag, Reason: `"items" is not one of users, orders`},
+		{Name: "timeout", Source: sdk_args.SourceFlag, Reason: "10ms is lower than the minimum 1s"},
+		{Name: "ratio", Source: sdk_args.SourceFlag, Reason: "2 is greater than the maximum 1"},
+		{Name: "token_file", Source: sdk_args.SourceFlag, Reason: "can't be set along with password"},
+	}, verr)
+	assert.Contains(t, err.Error(), "invalid arguments: hostname (default): is required; port (flag): ")
+}
+
+func TestSetupArgsValidationNestedArguments(t *testing.T) {
+	var args struct {
+		sdk_args.DefaultArgumentList
+		Database struct {
+			Port int `default:"0" min:"1" help:""`
+		}
+	}
+	os.Args = []string{"cmd"}
+
+	clearFlagSet()
+	err := sdk_args.SetupArgs(&args)
+	assert.EqualError(t, err, "invalid arguments: database_port (default): 0 is lower than the minimum 1")
+}
+
+func TestSetupArgsValidationMalformedTags(t *testing.T) {
+	os.Args = []string{"cmd"}
+
+	var badBound struct {
+		Port int `default:"1" min:"one" help:""`
+	}
+	clearFlagSet()
+	err := sdk_args.SetupArgs(&badBound)
+	assert.Error(t, err)
+	_, ok := err.(sdk_args.ValidationError)
+	assert.False(t, ok)
+
+	var badRegex struct {
+		Name string `default:"a" regex:"[" help:""`
+	}
+	clearFlagSet()
+	assert.Error(t, sdk_args.SetupArgs(&badRegex))
+
+	var unsupportedBound struct {
+		Verbose bool `default:"true" min:"1" help:""`
+	}
+	clearFlagSet()
+	assert.Error(t, sdk_args.SetupArgs(&unsupportedBound))
+}
@@ -0,0 +1,106 @@
+package args
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// stringSlice is a flag.Value setting a []string argument from comma-separated values. The flag can be repeated
+// to append values, and the first time it's set the default values are replaced.
+type stringSlice struct {
+	value   *[]string
+	changed bool
+}
+
+func newStringSlice(p *[]string) *stringSlice {
+	return &stringSlice{value: p}
+}
+
+func (s *stringSlice) setDefault(defaultValue string) error {
+	if defaultValue != "" {
+		*s.value = splitList(defaultValue)
+	}
+	return nil
+}
+
+// Set appends the comma-separated values to the argument.
+func (s *stringSlice) Set(value string) error {
+	if !s.changed {
+		*s.value = nil
+		s.changed = true
+	}
+	*s.value = append(*s.value, splitList(value)...)
+	return nil
+}
+
+// String returns the values separated by commas.
+func (s *stringSlice) String() string {
+	if s.value == nil {
+		return ""
+	}
+	return strings.Join(*s.value, ",")
+}
+
+// stringMap is a flag.Value setting a map[string]string argument from comma-separated key=value pairs. The flag
+// can be repeated to add pairs, and the first time it's set the default pairs are replaced.
+type stringMap struct {
+	value   *map[string]string
+	changed bool
+}
+
+func newStringMap(p *map[string]string) *stringMap {
+	return &stringMap{value: p}
+}
+
+func (m *stringMap) setDefault(defaultValue string) error {
+	if defaultValue == "" {
+		return nil
+	}
+	*m.value = map[string]string{}
+	return m.add(defaultValue)
+}
+
+// Set adds the comma-separated key=value pairs to the argument.
+func (m *stringMap) Set(value string) error {
+	if !m.changed {
+		*m.value = map[string]string{}
+		m.changed = true
+	}
+	return m.add(value)
+}
+
+func (m *stringMap) add(value string) error {
+	for _, pair := range splitList(value) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		(*m.value)[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return nil
+}
+
+// String returns the pairs sorted by key and separated by commas.
+func (m *stringMap) String() string {
+	if m.value == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*m.value))
+	for k, v := range *m.value {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// splitList splits comma-separated values, trimming spaces and discarding empty values.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
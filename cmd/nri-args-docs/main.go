@@ -0,0 +1,77 @@
+// Command nri-args-docs generates a sample agent configuration or definition file, or a Markdown reference for
+// the arguments bundled in the SDK. Integrations can generate the same files for their own arguments struct with
+// a program like this one, calling args.SampleConfig, args.SampleDefinition and args.Markdown, e.g. from a
+// go:generate directive.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/args"
+)
+
+type bundledArguments struct {
+	args.DefaultArgumentList
+}
+
+type bundledHTTPArguments struct {
+	args.DefaultArgumentList
+	args.HTTPClientArgumentList
+}
+
+func main() {
+	name := flag.String("name", "nri-integration", "Name of the integration in the sample configuration.")
+	format := flag.String("format", "yaml",
+		"Output format: yaml (sample configuration), definition (sample definition) or markdown (reference).")
+	description := flag.String("description", "", "Description of the integration in the sample definition.")
+	binary := flag.String("binary", "", "Path of the integration binary in the sample definition, ./bin/<name> by default.")
+	withHTTP := flag.Bool("http", false, "Include the HTTP client arguments.")
+	envPrefix := flag.String("env-prefix", "", "Prefix of the environment variables the arguments are read from.")
+	flag.Parse()
+
+	var arguments interface{} = &bundledArguments{}
+	if *withHTTP {
+		arguments = &bundledHTTPArguments{}
+	}
+
+	var opts []args.SetupOption
+	if *envPrefix != "" {
+		opts = append(opts, args.EnvPrefix(*envPrefix))
+	}
+
+	var out string
+	switch *format {
+	case "yaml":
+		config, err := args.SampleConfig(*name, arguments, opts...)
+		if err != nil {
+			fail(err)
+		}
+		out = string(config)
+	case "definition":
+		path := *binary
+		if path == "" {
+			path = "./bin/" + *name
+		}
+		definition, err := args.SampleDefinition(*name, *description, path)
+		if err != nil {
+			fail(err)
+		}
+		out = string(definition)
+	case "markdown":
+		reference, err := args.Markdown(arguments, opts...)
+		if err != nil {
+			fail(err)
+		}
+		out = reference
+	default:
+		fail(fmt.Errorf("unknown format %s", *format))
+	}
+	fmt.Print(out)
+}
+
+func fail(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
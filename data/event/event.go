@@ -5,6 +5,7 @@ import (
 	"time"
 
 	err "github.com/newrelic/infra-integrations-sdk/v4/data/errors"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 	agentEventPkg "github.com/newrelic/infrastructure-agent/pkg/event"
 )
 
@@ -46,7 +47,7 @@ func New(timestamp time.Time, summary, category string) (*Event, error) {
 		return nil, err.ParameterCannotBeEmpty("summary")
 	}
 	return &Event{
-		Timestamp:  timestamp.Unix(),
+		Timestamp:  precision.Timestamp(timestamp, precision.Get()),
 		Summary:    summary,
 		Category:   category,
 		Attributes: make(map[string]interface{}),
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 )
 
 func Test_Event_NewEvent(t *testing.T) {
@@ -51,3 +53,12 @@ func Test_Event_NewEventsWithAttributes(t *testing.T) {
 	assert.Equal(t, "category", e.Category)
 	assert.Equal(t, "attrVal", e.Attributes["attrKey"])
 }
+
+func Test_Event_NewEventWithMillisecondPrecision(t *testing.T) {
+	defer func() { _ = precision.Set(time.Second) }()
+	assert.NoError(t, precision.Set(time.Millisecond))
+
+	e, _ := New(time.Unix(10000000, 123456789), "summary", "category")
+
+	assert.Equal(t, int64(10000000123), e.Timestamp)
+}
@@ -0,0 +1,300 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SummaryBuilder accumulates individual observations and builds a metric of type summary out of them,
+// so callers don't need to compute count, average, sum, min and max by themselves.
+// It is safe for concurrent use. NaN observations are ignored.
+type SummaryBuilder struct {
+	lock  sync.Mutex
+	count float64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// NewSummaryBuilder creates an empty summary builder.
+func NewSummaryBuilder() *SummaryBuilder {
+	return &SummaryBuilder{}
+}
+
+// Observe adds a single observation to the builder.
+func (b *SummaryBuilder) Observe(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.count == 0 || value < b.min {
+		b.min = value
+	}
+	if b.count == 0 || value > b.max {
+		b.max = value
+	}
+	b.count++
+	b.sum += value
+}
+
+// Build creates a summary metric from the observations made so far.
+// When no observation has been made the average, min and max values are NaN (serialized as null).
+func (b *SummaryBuilder) Build(timestamp time.Time, name string) (Metric, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.count == 0 {
+		return NewSummary(timestamp, name, 0, math.NaN(), 0, math.NaN(), math.NaN())
+	}
+	return NewSummary(timestamp, name, b.count, b.sum/b.count, b.sum, b.min, b.max)
+}
+
+// PrometheusHistogramBuilder accumulates individual observations into a fixed set of buckets and builds
+// a Prometheus histogram out of them, taking care of ordering the buckets and computing the cumulative counts.
+// It is safe for concurrent use. NaN observations are ignored.
+type PrometheusHistogramBuilder struct {
+	lock   sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewPrometheusHistogramBuilder creates a histogram builder for the given bucket upper bounds.
+// Bounds don't need to be sorted. NaN, infinite and duplicated bounds are discarded, as the +Inf bucket
+// is implicitly given by the histogram sample count.
+func NewPrometheusHistogramBuilder(bounds ...float64) *PrometheusHistogramBuilder {
+	sorted := make([]float64, 0, len(bounds))
+	for _, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			continue
+		}
+		sorted = append(sorted, b)
+	}
+	sort.Float64s(sorted)
+
+	unique := sorted[:0]
+	for i, b := range sorted {
+		if i == 0 || b != sorted[i-1] {
+			unique = append(unique, b)
+		}
+	}
+
+	return &PrometheusHistogramBuilder{
+		bounds: unique,
+		counts: make([]uint64, len(unique)),
+	}
+}
+
+// Observe adds a single observation to the builder.
+func (b *PrometheusHistogramBuilder) Observe(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.count++
+	b.sum += value
+	// upper bounds are inclusive
+	if i := sort.SearchFloat64s(b.bounds, value); i < len(b.bounds) {
+		b.counts[i]++
+	}
+}
+
+// Build creates a Prometheus histogram from the observations made so far.
+func (b *PrometheusHistogramBuilder) Build(timestamp time.Time, name string) (*PrometheusHistogram, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ph, err := NewPrometheusHistogram(timestamp, name, b.count, b.sum)
+	if err != nil {
+		return nil, err
+	}
+
+	var cumulative uint64
+	for i, bound := range b.bounds {
+		cumulative += b.counts[i]
+		ph.AddBucket(cumulative, bound)
+	}
+	return ph, nil
+}
+
+// PrometheusSummaryBuilder accumulates individual observations and builds a Prometheus summary out of them.
+// Quantiles are estimated in a streaming fashion with the P² algorithm, so memory usage doesn't grow with the
+// number of observations.
+// It is safe for concurrent use. NaN observations are ignored.
+type PrometheusSummaryBuilder struct {
+	lock       sync.Mutex
+	estimators []*p2Estimator
+	count      uint64
+	sum        float64
+}
+
+// NewPrometheusSummaryBuilder creates a summary builder estimating the given quantiles, which must be in the
+// [0, 1] range.
+func NewPrometheusSummaryBuilder(quantiles ...float64) (*PrometheusSummaryBuilder, error) {
+	estimators := make([]*p2Estimator, 0, len(quantiles))
+	for _, q := range quantiles {
+		if math.IsNaN(q) || q < 0 || q > 1 {
+			return nil, fmt.Errorf("quantile (%v) must be between 0 and 1", q)
+		}
+		estimators = append(estimators, newP2Estimator(q))
+	}
+
+	return &PrometheusSummaryBuilder{
+		estimators: estimators,
+	}, nil
+}
+
+// Observe adds a single observation to the builder.
+func (b *PrometheusSummaryBuilder) Observe(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.count++
+	b.sum += value
+	for _, e := range b.estimators {
+		e.observe(value)
+	}
+}
+
+// Build creates a Prometheus summary from the observations made so far. Quantiles are only added once
+// at least one observation has been made.
+func (b *PrometheusSummaryBuilder) Build(timestamp time.Time, name string) (*PrometheusSummary, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ps, err := NewPrometheusSummary(timestamp, name, b.count, b.sum)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.count > 0 {
+		for _, e := range b.estimators {
+			ps.AddQuantile(e.quantile, e.estimate())
+		}
+	}
+	return ps, nil
+}
+
+// p2Estimator implements the P² algorithm for the dynamic calculation of quantiles without storing observations.
+// See: R. Jain and I. Chlamtac, "The P² algorithm for dynamic calculation of quantiles and histograms without
+// storing observations", Communications of the ACM, 1985.
+type p2Estimator struct {
+	quantile float64
+	count    int
+	// marker heights
+	heights [5]float64
+	// actual marker positions
+	positions [5]float64
+	// desired marker positions
+	desired [5]float64
+	// desired marker positions increments
+	increments [5]float64
+}
+
+func newP2Estimator(quantile float64) *p2Estimator {
+	return &p2Estimator{
+		quantile:   quantile,
+		positions:  [5]float64{1, 2, 3, 4, 5},
+		desired:    [5]float64{1, 1 + 2*quantile, 1 + 4*quantile, 3 + 2*quantile, 5},
+		increments: [5]float64{0, quantile / 2, quantile, (1 + quantile) / 2, 1},
+	}
+}
+
+func (e *p2Estimator) observe(value float64) {
+	// the first five observations are stored as they are, sorted
+	if e.count < 5 {
+		e.heights[e.count] = value
+		e.count++
+		sort.Float64s(e.heights[:e.count])
+		return
+	}
+	e.count++
+
+	// find the cell k the observation falls in, adjusting the extreme markers
+	var k int
+	switch {
+	case value < e.heights[0]:
+		e.heights[0] = value
+		k = 0
+	case value >= e.heights[4]:
+		e.heights[4] = value
+		k = 3
+	default:
+		for k = 0; k < 3; k++ {
+			if value < e.heights[k+1] {
+				break
+			}
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		e.positions[i]++
+	}
+	for i := range e.desired {
+		e.desired[i] += e.increments[i]
+	}
+
+	// adjust the heights of the middle markers if they are off their desired positions
+	for i := 1; i < 4; i++ {
+		d := e.desired[i] - e.positions[i]
+		if (d >= 1 && e.positions[i+1]-e.positions[i] > 1) || (d <= -1 && e.positions[i-1]-e.positions[i] < -1) {
+			sign := 1.0
+			if d < 0 {
+				sign = -1.0
+			}
+			h := e.parabolic(i, sign)
+			if e.heights[i-1] < h && h < e.heights[i+1] {
+				e.heights[i] = h
+			} else {
+				e.heights[i] = e.linear(i, sign)
+			}
+			e.positions[i] += sign
+		}
+	}
+}
+
+func (e *p2Estimator) parabolic(i int, d float64) float64 {
+	n, q := e.positions, e.heights
+	return q[i] + d/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+d)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-d)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+func (e *p2Estimator) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return e.heights[i] + d*(e.heights[j]-e.heights[i])/(e.positions[j]-e.positions[i])
+}
+
+func (e *p2Estimator) estimate() float64 {
+	if e.count == 0 {
+		return math.NaN()
+	}
+	// not enough observations for the markers yet, so pick the nearest rank
+	if e.count <= 5 {
+		i := int(math.Ceil(e.quantile*float64(e.count))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return e.heights[i]
+	}
+
+	switch e.quantile {
+	case 0:
+		return e.heights[0]
+	case 1:
+		return e.heights[4]
+	default:
+		return e.heights[2]
+	}
+}
@@ -0,0 +1,121 @@
+package metric
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SummaryBuilder_Build(t *testing.T) {
+	b := NewSummaryBuilder()
+	for _, v := range []float64{4, 1, math.NaN(), 7} {
+		b.Observe(v)
+	}
+
+	m, err := b.Build(now, "latency")
+	require.NoError(t, err)
+
+	s := m.(*summary)
+	assert.Equal(t, "latency", s.Name)
+	assert.Equal(t, float64(3), *s.Value.Count)
+	assert.Equal(t, float64(12), *s.Value.Sum)
+	assert.Equal(t, float64(4), *s.Value.Average)
+	assert.Equal(t, float64(1), *s.Value.Min)
+	assert.Equal(t, float64(7), *s.Value.Max)
+}
+
+func Test_SummaryBuilder_BuildWithoutObservations(t *testing.T) {
+	m, err := NewSummaryBuilder().Build(now, "latency")
+	require.NoError(t, err)
+
+	s := m.(*summary)
+	assert.Equal(t, float64(0), *s.Value.Count)
+	assert.Nil(t, s.Value.Average)
+	assert.Nil(t, s.Value.Min)
+	assert.Nil(t, s.Value.Max)
+}
+
+func Test_SummaryBuilder_CannotBuildWithEmptyName(t *testing.T) {
+	m, err := NewSummaryBuilder().Build(now, "")
+	assert.Nil(t, m)
+	assert.Error(t, err)
+}
+
+func Test_PrometheusHistogramBuilder_Build(t *testing.T) {
+	b := NewPrometheusHistogramBuilder(10, 1, math.Inf(1), 5, 5, math.NaN())
+	for _, v := range []float64{0.5, 1, 3, 7, 12, math.NaN()} {
+		b.Observe(v)
+	}
+
+	ph, err := b.Build(now, "latency")
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(5), *ph.Value.SampleCount)
+	assert.Equal(t, 23.5, *ph.Value.SampleSum)
+	require.Len(t, ph.Value.Buckets, 3)
+
+	expected := []struct {
+		bound float64
+		count uint64
+	}{
+		{1, 2},
+		{5, 3},
+		{10, 4},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.bound, *ph.Value.Buckets[i].UpperBound)
+		assert.Equal(t, e.count, *ph.Value.Buckets[i].CumulativeCount)
+	}
+}
+
+func Test_PrometheusSummaryBuilder_InvalidQuantile(t *testing.T) {
+	for _, q := range []float64{-0.1, 1.1, math.NaN()} {
+		b, err := NewPrometheusSummaryBuilder(q)
+		assert.Nil(t, b)
+		assert.Error(t, err)
+	}
+}
+
+func Test_PrometheusSummaryBuilder_FewObservations(t *testing.T) {
+	b, err := NewPrometheusSummaryBuilder(0, 0.5, 1)
+	require.NoError(t, err)
+
+	ps, err := b.Build(now, "latency")
+	require.NoError(t, err)
+	assert.Empty(t, ps.Value.Quantiles)
+
+	for _, v := range []float64{3, 1, 2} {
+		b.Observe(v)
+	}
+
+	ps, err = b.Build(now, "latency")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), *ps.Value.SampleCount)
+	assert.Equal(t, float64(6), *ps.Value.SampleSum)
+	require.Len(t, ps.Value.Quantiles, 3)
+	assert.Equal(t, float64(1), *ps.Value.Quantiles[0].Value)
+	assert.Equal(t, float64(2), *ps.Value.Quantiles[1].Value)
+	assert.Equal(t, float64(3), *ps.Value.Quantiles[2].Value)
+}
+
+func Test_PrometheusSummaryBuilder_EstimatesQuantiles(t *testing.T) {
+	b, err := NewPrometheusSummaryBuilder(0, 0.5, 0.9, 0.99, 1)
+	require.NoError(t, err)
+
+	// uniformly distributed observations between 0 and 1000
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 100000; i++ {
+		b.Observe(r.Float64() * 1000)
+	}
+
+	ps, err := b.Build(now, "latency")
+	require.NoError(t, err)
+	require.Len(t, ps.Value.Quantiles, 5)
+
+	for _, q := range ps.Value.Quantiles {
+		assert.InDelta(t, *q.Quantile*1000, *q.Value, 10, "quantile %v", *q.Quantile)
+	}
+}
@@ -0,0 +1,151 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/internal/markdown"
+)
+
+// Definition describes a metric emitted by an integration.
+type Definition struct {
+	Name        string
+	Type        SourceType
+	Unit        string
+	Description string
+	// Dimensions lists the dimensions the metric may have. Emitting any other dimension fails the check.
+	Dimensions []string
+}
+
+// definitionYAML is the YAML representation of a Definition, using readable source type names.
+type definitionYAML struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Unit        string   `yaml:"unit,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Dimensions  []string `yaml:"dimensions,omitempty"`
+}
+
+// Catalog is a registry of the metrics an integration emits. It can check emitted metrics against their
+// declaration and be dumped as documentation.
+// It is safe for concurrent use.
+type Catalog struct {
+	lock        sync.RWMutex
+	definitions map[string]Definition
+}
+
+// NewCatalog creates an empty catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{
+		definitions: map[string]Definition{},
+	}
+}
+
+// Declare adds the definitions to the catalog. An error is returned if any name is empty, already declared or
+// its source type is unknown.
+func (c *Catalog) Declare(definitions ...Definition) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, d := range definitions {
+		if len(d.Name) == 0 {
+			return errors.New("metric name cannot be empty")
+		}
+		if _, ok := c.definitions[d.Name]; ok {
+			return fmt.Errorf("metric %s is already declared", d.Name)
+		}
+		if d.Type.String() == "" {
+			return fmt.Errorf("metric %s has an unknown source type", d.Name)
+		}
+		c.definitions[d.Name] = d
+	}
+	return nil
+}
+
+// Lookup returns the definition of the metric with the given name, and false if it's not declared.
+func (c *Catalog) Lookup(name string) (Definition, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	d, ok := c.definitions[name]
+	return d, ok
+}
+
+// Definitions returns all the definitions in the catalog, sorted by metric name.
+func (c *Catalog) Definitions() []Definition {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	definitions := make([]Definition, 0, len(c.definitions))
+	for _, d := range c.definitions {
+		definitions = append(definitions, d)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+// Check returns an error if the metric is not declared in the catalog, its declared source type doesn't
+// match or it has dimensions not declared in the catalog.
+func (c *Catalog) Check(m Metric) error {
+	d, ok := c.Lookup(m.GetName())
+	if !ok {
+		return fmt.Errorf("metric %s is not declared", m.GetName())
+	}
+	if d.Type != m.GetType() {
+		return fmt.Errorf("metric %s is declared as %s but emitted as %s", m.GetName(), d.Type, m.GetType())
+	}
+
+	declared := make(map[string]bool, len(d.Dimensions))
+	for _, dim := range d.Dimensions {
+		declared[dim] = true
+	}
+	var undeclared []string
+	for dim := range attributesOf(m) {
+		if !declared[dim] {
+			undeclared = append(undeclared, dim)
+		}
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return fmt.Errorf("metric %s has undeclared dimensions: %s", m.GetName(), strings.Join(undeclared, ", "))
+	}
+	return nil
+}
+
+// YAML dumps the catalog as a YAML list of definitions.
+func (c *Catalog) YAML() ([]byte, error) {
+	var out []definitionYAML
+	for _, d := range c.Definitions() {
+		out = append(out, definitionYAML{
+			Name:        d.Name,
+			Type:        d.Type.String(),
+			Unit:        d.Unit,
+			Description: d.Description,
+			Dimensions:  d.Dimensions,
+		})
+	}
+	return yaml.Marshal(out)
+}
+
+// Markdown dumps the catalog as a Markdown table.
+func (c *Catalog) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("| Name | Type | Unit | Description | Dimensions |\n")
+	sb.WriteString("|------|------|------|-------------|------------|\n")
+	for _, d := range c.Definitions() {
+		dims := make([]string, 0, len(d.Dimensions))
+		for _, dim := range d.Dimensions {
+			dims = append(dims, "`"+markdown.EscapeCell(dim)+"`")
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s | %s |\n", markdown.EscapeCell(d.Name), d.Type,
+			markdown.EscapeCell(d.Unit), markdown.EscapeCell(d.Description), strings.Join(dims, ", ")))
+	}
+	return sb.String()
+}
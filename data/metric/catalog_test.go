@@ -0,0 +1,82 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	c := NewCatalog()
+	require.NoError(t, c.Declare(
+		Definition{Name: "db.queries", Type: COUNT, Unit: "queries", Description: "Executed queries", Dimensions: []string{"db"}},
+		Definition{Name: "db.connections", Type: GAUGE, Description: "Open | idle connections"},
+	))
+	return c
+}
+
+func Test_Catalog_Declare(t *testing.T) {
+	c := newTestCatalog(t)
+
+	d, ok := c.Lookup("db.queries")
+	assert.True(t, ok)
+	assert.Equal(t, "queries", d.Unit)
+	_, ok = c.Lookup("missing")
+	assert.False(t, ok)
+
+	assert.Error(t, c.Declare(Definition{Type: GAUGE}))
+	assert.Error(t, c.Declare(Definition{Name: "db.queries", Type: GAUGE}))
+	assert.Error(t, c.Declare(Definition{Name: "unknown", Type: SourceType(99)}))
+
+	definitions := c.Definitions()
+	require.Len(t, definitions, 2)
+	assert.Equal(t, "db.connections", definitions[0].Name)
+	assert.Equal(t, "db.queries", definitions[1].Name)
+}
+
+func Test_Catalog_Check(t *testing.T) {
+	c := newTestCatalog(t)
+
+	count, _ := NewCount(now, "db.queries", 1)
+	assert.NoError(t, count.AddDimension("db", "orders"))
+	assert.NoError(t, c.Check(count))
+
+	assert.NoError(t, count.AddTypedDimension("shard", 1))
+	assert.NoError(t, count.AddDimension("host", "db-1"))
+	assert.EqualError(t, c.Check(count), "metric db.queries has undeclared dimensions: host, shard")
+
+	gauge, _ := NewGauge(now, "db.queries", 1)
+	assert.EqualError(t, c.Check(gauge), "metric db.queries is declared as count but emitted as gauge")
+
+	undeclared, _ := NewGauge(now, "db.undeclared", 1)
+	assert.EqualError(t, c.Check(undeclared), "metric db.undeclared is not declared")
+}
+
+func Test_Catalog_YAML(t *testing.T) {
+	out, err := newTestCatalog(t).YAML()
+	require.NoError(t, err)
+
+	assert.Equal(t, `- name: db.connections
+  type: gauge
+  description: Open | idle connections
+- name: db.queries
+  type: count
+  unit: queries
+  description: Executed queries
+  dimensions:
+  - db
+`, string(out))
+}
+
+func Test_Catalog_Markdown(t *testing.T) {
+	assert.Equal(t, "| Name | Type | Unit | Description | Dimensions |\n"+
+		"|------|------|------|-------------|------------|\n"+
+		"| `db.connections` | gauge |  | Open \\| idle connections |  |\n"+
+		"| `db.queries` | count | queries | Executed queries | `db` |\n",
+		newTestCatalog(t).Markdown())
+
+	c := NewCatalog()
+	require.NoError(t, c.Declare(Definition{Name: "db.a|b", Type: GAUGE, Unit: "ops|s", Dimensions: []string{"x|y"}}))
+	assert.Contains(t, c.Markdown(), "| `db.a\\|b` | gauge | ops\\|s |  | `x\\|y` |\n")
+}
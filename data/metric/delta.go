@@ -0,0 +1,161 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/persist"
+)
+
+var (
+	// ErrNoPreviousValue is returned when there is no stored value to compute a difference with,
+	// usually on the first run of the integration.
+	ErrNoPreviousValue = errors.New("no previous value to compute the difference with")
+	// ErrClockSkew is returned when a value is not newer than the previously stored one.
+	ErrClockSkew = errors.New("value is not newer than the previously stored one")
+)
+
+// Difference is the result of comparing a cumulative value with the one stored in a previous run.
+type Difference struct {
+	// Delta is the increase of the value since the previous run. When the counter was reset it's the current value.
+	Delta float64
+	// Elapsed is the time between the previous and the current values.
+	Elapsed time.Duration
+	// Reset is true when the current value is lower than the previous one, so the counter is assumed to be reset.
+	Reset bool
+}
+
+// Rate returns the per-second rate of the difference.
+func (d Difference) Rate() float64 {
+	return d.Delta / d.Elapsed.Seconds()
+}
+
+// DeltaCalculator converts cumulative values into deltas or per-second rates between integration runs,
+// keeping the previous values in a persist.Storer. Note the storer must be saved for values to be kept
+// between runs.
+type DeltaCalculator struct {
+	storer persist.Storer
+}
+
+type storedValue struct {
+	Value float64 `json:"value"`
+	// Timestamp in nanoseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+// NewDeltaCalculator creates a delta calculator backed by the given storer.
+func NewDeltaCalculator(storer persist.Storer) *DeltaCalculator {
+	return &DeltaCalculator{storer: storer}
+}
+
+// Difference stores the value for the given key and returns its difference with the previously stored one.
+// ErrNoPreviousValue is returned when there was no previous value, and ErrClockSkew when the previous value is
+// not older than the current one. In both cases the current value is stored so following calls can compute it.
+func (c *DeltaCalculator) Difference(key string, timestamp time.Time, value float64) (Difference, error) {
+	var previous storedValue
+	_, err := c.storer.Get(key, &previous)
+	c.storer.Set(key, storedValue{Value: value, Timestamp: timestamp.UnixNano()})
+
+	if err == persist.ErrNotFound {
+		return Difference{}, ErrNoPreviousValue
+	}
+	if err != nil {
+		return Difference{}, err
+	}
+
+	elapsed := time.Duration(timestamp.UnixNano() - previous.Timestamp)
+	if elapsed <= 0 {
+		return Difference{}, ErrClockSkew
+	}
+
+	if value < previous.Value {
+		return Difference{Delta: value, Elapsed: elapsed, Reset: true}, nil
+	}
+	return Difference{Delta: value - previous.Value, Elapsed: elapsed}, nil
+}
+
+// Convert computes client side the value the agent would compute for a cumulative metric: a cumulative-count is
+// converted into a count holding the delta since the previous run, and a cumulative-rate into a gauge holding the
+// per-second rate since the previous run. The namespace (i.e. the entity name) distinguishes metrics with the
+// same name and dimensions from different sources. Other metric types are returned as they are.
+// The errors returned by Difference are returned as well.
+func (c *DeltaCalculator) Convert(namespace string, m Metric) (Metric, error) {
+	var value float64
+	var base metricBase
+	switch cm := m.(type) {
+	case *cumulativeCount:
+		value = cm.Value
+		base = cm.metricBase.clone()
+	case *cumulativeRate:
+		value = cm.Value
+		base = cm.metricBase.clone()
+	default:
+		return m, nil
+	}
+
+	diff, err := c.Difference(namespace+"|"+SeriesKey(m), m.GetTimestamp(), value)
+	if err != nil {
+		return nil, fmt.Errorf("can't compute %s for metric %s: %w", m.GetType(), m.GetName(), err)
+	}
+
+	if m.GetType() == CUMULATIVE_COUNT {
+		base.Type = SourcesTypeToName[COUNT]
+		return &count{metricBase: base, Value: diff.Delta}, nil
+	}
+	base.Type = SourcesTypeToName[GAUGE]
+	return &gauge{metricBase: base, Value: diff.Rate()}, nil
+}
+
+// Save persists the stored values.
+func (c *DeltaCalculator) Save() error {
+	return c.storer.Save()
+}
+
+// SeriesKey returns a string identifying the metric series, composed by the metric name and its sorted dimensions.
+func SeriesKey(m Metric) string {
+	return seriesKey(m.GetName(), attributesOf(m))
+}
+
+// DimensionsSeriesKey returns the key that SeriesKey returns for a metric with the given name and dimensions,
+// without creating the metric. An error is returned if any dimension value is not supported (see AttributeValue).
+func DimensionsSeriesKey(name string, dimensions map[string]interface{}) (string, error) {
+	dims := make(map[string]interface{}, len(dimensions))
+	for k, v := range dimensions {
+		value, err := AttributeValue(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid dimension %s: %s", k, err)
+		}
+		dims[k] = value
+	}
+	return seriesKey(name, dims), nil
+}
+
+func seriesKey(name string, dims map[string]interface{}) string {
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf(",%s=%v", k, dims[k]))
+	}
+	return sb.String()
+}
+
+// attributesOf returns both the string and the typed dimensions of the metric.
+func attributesOf(m Metric) map[string]interface{} {
+	if h, ok := m.(interface{ attributes() map[string]interface{} }); ok {
+		return h.attributes()
+	}
+	dims := map[string]interface{}{}
+	for k, v := range m.GetDimensions() {
+		dims[k] = v
+	}
+	return dims
+}
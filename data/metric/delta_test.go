@@ -0,0 +1,90 @@
+package metric
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/persist"
+)
+
+func Test_DeltaCalculator_Difference(t *testing.T) {
+	c := NewDeltaCalculator(persist.NewInMemoryStore())
+	t0 := time.Unix(10000000, 0)
+
+	_, err := c.Difference("key", t0, 10)
+	assert.Equal(t, ErrNoPreviousValue, err)
+
+	diff, err := c.Difference("key", t0.Add(10*time.Second), 30)
+	require.NoError(t, err)
+	assert.Equal(t, Difference{Delta: 20, Elapsed: 10 * time.Second}, diff)
+	assert.Equal(t, float64(2), diff.Rate())
+
+	// counter reset
+	diff, err = c.Difference("key", t0.Add(20*time.Second), 5)
+	require.NoError(t, err)
+	assert.Equal(t, Difference{Delta: 5, Elapsed: 10 * time.Second, Reset: true}, diff)
+
+	// clock skew stores the new value anyway
+	_, err = c.Difference("key", t0, 7)
+	assert.Equal(t, ErrClockSkew, err)
+	diff, err = c.Difference("key", t0.Add(time.Second), 8)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), diff.Delta)
+}
+
+func Test_DeltaCalculator_Convert(t *testing.T) {
+	c := NewDeltaCalculator(persist.NewInMemoryStore())
+	t0 := time.Unix(10000000, 0)
+
+	cc, _ := NewCumulativeCount(t0, "requests", 100)
+	_ = cc.AddDimension("path", "/")
+	cr, _ := NewCumulativeRate(t0, "bytes", 1000)
+	g, _ := NewGauge(t0, "gauge", 1)
+
+	_, err := c.Convert("entity", cc)
+	assert.True(t, errors.Is(err, ErrNoPreviousValue))
+	_, err = c.Convert("entity", cr)
+	assert.True(t, errors.Is(err, ErrNoPreviousValue))
+	converted, err := c.Convert("entity", g)
+	require.NoError(t, err)
+	assert.Equal(t, g, converted)
+
+	cc, _ = NewCumulativeCount(t0.Add(10*time.Second), "requests", 150)
+	_ = cc.AddDimension("path", "/")
+	converted, err = c.Convert("entity", cc)
+	require.NoError(t, err)
+	assert.Equal(t, COUNT, converted.GetType())
+	assert.Equal(t, float64(50), converted.(NumericMetric).GetValue())
+	assert.Equal(t, "/", converted.Dimension("path"))
+
+	cr, _ = NewCumulativeRate(t0.Add(10*time.Second), "bytes", 3000)
+	converted, err = c.Convert("entity", cr)
+	require.NoError(t, err)
+	assert.Equal(t, GAUGE, converted.GetType())
+	assert.Equal(t, float64(200), converted.(NumericMetric).GetValue())
+
+	// same metric from a different entity has its own series
+	cc, _ = NewCumulativeCount(t0.Add(10*time.Second), "requests", 150)
+	_ = cc.AddDimension("path", "/")
+	_, err = c.Convert("other-entity", cc)
+	assert.True(t, errors.Is(err, ErrNoPreviousValue))
+}
+
+func Test_SeriesKey(t *testing.T) {
+	g, _ := NewGauge(now, "gauge", 1)
+	_ = g.AddDimension("b", "2")
+	_ = g.AddTypedDimension("a", 1)
+
+	assert.Equal(t, "gauge,a=1,b=2", SeriesKey(g))
+
+	key, err := DimensionsSeriesKey("gauge", map[string]interface{}{"b": "2", "a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, SeriesKey(g), key)
+
+	_, err = DimensionsSeriesKey("gauge", map[string]interface{}{"a": []int{}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,272 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	err "github.com/newrelic/infra-integrations-sdk/v4/data/errors"
+)
+
+// Scale limits for exponential histograms. With scale s the base of the histogram is 2^(2^-s), so the highest
+// scale gives the finest resolution.
+const (
+	ExponentialHistogramMinScale = -4
+	ExponentialHistogramMaxScale = 8
+)
+
+// ExponentialHistogram represents a base-2 exponential histogram. Bucket boundaries are given by the scale
+// following the Prometheus native histogram convention: with base = 2^(2^-scale), the bucket with index i
+// counts the observations in the (base^(i-1), base^i] range for the positive side, and the mirrored range
+// for the negative side.
+type ExponentialHistogram struct {
+	metricBase
+	Value ExponentialHistogramValue `json:"value"`
+}
+
+// ExponentialHistogramValue represents the Value type for an exponential histogram.
+type ExponentialHistogramValue struct {
+	SampleCount *uint64  `json:"sample_count,omitempty"`
+	SampleSum   *float64 `json:"sample_sum,omitempty"`
+	Scale       int32    `json:"scale"`
+	// ZeroCount is the number of observations whose absolute value is lower or equal than ZeroThreshold.
+	ZeroCount     uint64              `json:"zero_count"`
+	ZeroThreshold float64             `json:"zero_threshold"`
+	Positive      *ExponentialBuckets `json:"positive,omitempty"`
+	Negative      *ExponentialBuckets `json:"negative,omitempty"`
+}
+
+// ExponentialBuckets stores the populated buckets of one side of an exponential histogram.
+// Counts holds the (non cumulative) count of every bucket covered by the spans, in order.
+type ExponentialBuckets struct {
+	Spans  []BucketSpan `json:"spans"`
+	Counts []uint64     `json:"counts"`
+}
+
+// BucketSpan defines a sequence of consecutive buckets. The offset of the first span is the index of its
+// first bucket, while the offset of the following spans is the gap with the end of the previous span.
+type BucketSpan struct {
+	Offset int32  `json:"offset"`
+	Length uint32 `json:"length"`
+}
+
+// NewExponentialHistogram creates a new metric of type exponential histogram with no populated buckets.
+func NewExponentialHistogram(timestamp time.Time, name string, scale int32, sampleCount uint64, sampleSum float64) (*ExponentialHistogram, error) {
+	if len(name) == 0 {
+		return nil, err.ParameterCannotBeEmpty("name")
+	}
+	if scale < ExponentialHistogramMinScale || scale > ExponentialHistogramMaxScale {
+		return nil, fmt.Errorf("scale (%d) must be between %d and %d", scale, ExponentialHistogramMinScale, ExponentialHistogramMaxScale)
+	}
+
+	return &ExponentialHistogram{
+		metricBase: newMetricBase(timestamp, name, EXPONENTIAL_HISTOGRAM),
+		Value: ExponentialHistogramValue{
+			SampleCount: &sampleCount,
+			SampleSum:   asFloatPtr(sampleSum),
+			Scale:       scale,
+		},
+	}, nil
+}
+
+// SetZeroBucket sets the count of observations whose absolute value is lower or equal than the threshold.
+func (eh *ExponentialHistogram) SetZeroBucket(count uint64, threshold float64) error {
+	if math.IsNaN(threshold) || math.IsInf(threshold, 0) || threshold < 0 {
+		return fmt.Errorf("zero threshold (%v) must be a non-negative finite number", threshold)
+	}
+	eh.Value.ZeroCount = count
+	eh.Value.ZeroThreshold = threshold
+	return nil
+}
+
+// AddPositiveBucket adds the given count to the positive bucket with the given index.
+func (eh *ExponentialHistogram) AddPositiveBucket(index int32, count uint64) {
+	eh.Value.Positive = addToBuckets(eh.Value.Positive, map[int32]uint64{index: count})
+}
+
+// AddNegativeBucket adds the given count to the negative bucket with the given index.
+func (eh *ExponentialHistogram) AddNegativeBucket(index int32, count uint64) {
+	eh.Value.Negative = addToBuckets(eh.Value.Negative, map[int32]uint64{index: count})
+}
+
+// Merge adds the observations of another exponential histogram into this one. When scales differ the
+// result takes the lowest of both, and when zero thresholds differ the result takes the widest of both,
+// moving the buckets that fall within it into the zero bucket.
+func (eh *ExponentialHistogram) Merge(other *ExponentialHistogram) error {
+	if other == nil {
+		return err.ParameterCannotBeEmpty("histogram")
+	}
+	if eh.Name != other.Name {
+		return fmt.Errorf("cannot merge histogram %s into %s", other.Name, eh.Name)
+	}
+
+	scale := eh.Value.Scale
+	if other.Value.Scale < scale {
+		scale = other.Value.Scale
+	}
+	threshold := math.Max(eh.Value.ZeroThreshold, other.Value.ZeroThreshold)
+
+	positive := downscale(eh.Value.Positive.indexed(), eh.Value.Scale-scale)
+	negative := downscale(eh.Value.Negative.indexed(), eh.Value.Scale-scale)
+	mergeIndexed(positive, downscale(other.Value.Positive.indexed(), other.Value.Scale-scale))
+	mergeIndexed(negative, downscale(other.Value.Negative.indexed(), other.Value.Scale-scale))
+
+	zeroCount := eh.Value.ZeroCount + other.Value.ZeroCount
+	zeroCount += widenZeroBucket(positive, scale, threshold)
+	zeroCount += widenZeroBucket(negative, scale, threshold)
+
+	sampleCount := other.sampleCount()
+	if eh.Value.SampleCount != nil {
+		sampleCount += *eh.Value.SampleCount
+	}
+	var sampleSum float64
+	if eh.Value.SampleSum != nil {
+		sampleSum += *eh.Value.SampleSum
+	}
+	if other.Value.SampleSum != nil {
+		sampleSum += *other.Value.SampleSum
+	}
+
+	eh.Value.SampleCount = &sampleCount
+	eh.Value.SampleSum = asFloatPtr(sampleSum)
+	eh.Value.Scale = scale
+	eh.Value.ZeroCount = zeroCount
+	eh.Value.ZeroThreshold = threshold
+	eh.Value.Positive = addToBuckets(nil, positive)
+	eh.Value.Negative = addToBuckets(nil, negative)
+	return nil
+}
+
+// MarshalJSON serializes the exponential histogram, fulfilling Marshaler interface.
+// A zero threshold that is not a finite number is serialized as null, see NonFinitePolicy.
+func (eh *ExponentialHistogram) MarshalJSON() ([]byte, error) {
+	type valueJSON struct {
+		ExponentialHistogramValue
+		ZeroThreshold *float64 `json:"zero_threshold"`
+	}
+	return json.Marshal(struct {
+		baseJSON
+		Value valueJSON `json:"value"`
+	}{eh.baseJSON(), valueJSON{eh.Value, asFloatPtr(eh.Value.ZeroThreshold)}})
+}
+
+// GetValue returns the exponential histogram value
+func (eh *ExponentialHistogram) GetValue() ExponentialHistogramValue {
+	return eh.Value
+}
+
+// Clone returns a deep copy of the exponential histogram
+func (eh *ExponentialHistogram) Clone() Metric {
+	c := *eh
+	c.metricBase = eh.metricBase.clone()
+	c.Value.SampleCount = cloneUintPtr(eh.Value.SampleCount)
+	c.Value.SampleSum = cloneFloatPtr(eh.Value.SampleSum)
+	c.Value.Positive = eh.Value.Positive.clone()
+	c.Value.Negative = eh.Value.Negative.clone()
+	return &c
+}
+
+func (eh *ExponentialHistogram) sampleCount() uint64 {
+	if eh.Value.SampleCount == nil {
+		return 0
+	}
+	return *eh.Value.SampleCount
+}
+
+func (b *ExponentialBuckets) clone() *ExponentialBuckets {
+	if b == nil {
+		return nil
+	}
+	return &ExponentialBuckets{
+		Spans:  append([]BucketSpan(nil), b.Spans...),
+		Counts: append([]uint64(nil), b.Counts...),
+	}
+}
+
+// indexed returns the buckets as a map of bucket index to count.
+func (b *ExponentialBuckets) indexed() map[int32]uint64 {
+	indexed := map[int32]uint64{}
+	if b == nil {
+		return indexed
+	}
+
+	var index int32
+	c := 0
+	for i, span := range b.Spans {
+		if i == 0 {
+			index = span.Offset
+		} else {
+			index += span.Offset
+		}
+		for j := uint32(0); j < span.Length && c < len(b.Counts); j++ {
+			indexed[index] += b.Counts[c]
+			index++
+			c++
+		}
+	}
+	return indexed
+}
+
+// addToBuckets returns the spans representation of the given buckets plus the indexed counts.
+func addToBuckets(b *ExponentialBuckets, counts map[int32]uint64) *ExponentialBuckets {
+	indexed := b.indexed()
+	mergeIndexed(indexed, counts)
+	if len(indexed) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(indexed))
+	for i := range indexed {
+		indexes = append(indexes, int(i))
+	}
+	sort.Ints(indexes)
+
+	result := &ExponentialBuckets{}
+	for n, i := range indexes {
+		index := int32(i)
+		switch {
+		case n == 0:
+			result.Spans = append(result.Spans, BucketSpan{Offset: index, Length: 1})
+		case index == int32(indexes[n-1])+1:
+			result.Spans[len(result.Spans)-1].Length++
+		default:
+			result.Spans = append(result.Spans, BucketSpan{Offset: index - int32(indexes[n-1]) - 1, Length: 1})
+		}
+		result.Counts = append(result.Counts, indexed[index])
+	}
+	return result
+}
+
+func mergeIndexed(dst, src map[int32]uint64) {
+	for i, c := range src {
+		dst[i] += c
+	}
+}
+
+// downscale reduces the resolution of the indexed buckets by the given scale delta.
+func downscale(indexed map[int32]uint64, delta int32) map[int32]uint64 {
+	if delta <= 0 {
+		return indexed
+	}
+	result := make(map[int32]uint64, len(indexed))
+	for i, c := range indexed {
+		// (base^(i-1), base^i] maps to the bucket containing its upper bound at the lower scale
+		result[((i-1)>>uint32(delta))+1] += c
+	}
+	return result
+}
+
+// widenZeroBucket removes from the indexed buckets the ones within the zero threshold and returns their count.
+func widenZeroBucket(indexed map[int32]uint64, scale int32, threshold float64) uint64 {
+	var count uint64
+	for i, c := range indexed {
+		upperBound := math.Exp2(float64(i) * math.Exp2(-float64(scale)))
+		if upperBound <= threshold {
+			count += c
+			delete(indexed, i)
+		}
+	}
+	return count
+}
@@ -0,0 +1,140 @@
+package metric
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Metric_CreateExponentialHistogram(t *testing.T) {
+	eh, err := NewExponentialHistogram(now, "some-histogram", 3, 10, 25)
+	require.NoError(t, err)
+
+	assert.Equal(t, "exponential-histogram", eh.Type)
+	assert.Equal(t, "some-histogram", eh.Name)
+	assert.Equal(t, now.Unix(), eh.Timestamp)
+	assert.Equal(t, int32(3), eh.Value.Scale)
+	assert.Equal(t, uint64(10), *eh.Value.SampleCount)
+	assert.Equal(t, float64(25), *eh.Value.SampleSum)
+}
+
+func Test_Metric_CannotCreateExponentialHistogram(t *testing.T) {
+	eh, err := NewExponentialHistogram(now, "", 0, 1, 1)
+	assert.Nil(t, eh)
+	assert.Error(t, err)
+
+	eh, err = NewExponentialHistogram(now, "some-histogram", ExponentialHistogramMaxScale+1, 1, 1)
+	assert.Nil(t, eh)
+	assert.Error(t, err)
+
+	eh, err = NewExponentialHistogram(now, "some-histogram", ExponentialHistogramMinScale-1, 1, 1)
+	assert.Nil(t, eh)
+	assert.Error(t, err)
+}
+
+func Test_Metric_ExponentialHistogramAddBuckets(t *testing.T) {
+	eh, err := NewExponentialHistogram(now, "some-histogram", 0, 10, 25)
+	require.NoError(t, err)
+
+	eh.AddPositiveBucket(4, 1)
+	eh.AddPositiveBucket(1, 2)
+	eh.AddPositiveBucket(2, 3)
+	eh.AddPositiveBucket(1, 1)
+	eh.AddNegativeBucket(-2, 2)
+
+	assert.Equal(t, []BucketSpan{{Offset: 1, Length: 2}, {Offset: 1, Length: 1}}, eh.Value.Positive.Spans)
+	assert.Equal(t, []uint64{3, 3, 1}, eh.Value.Positive.Counts)
+	assert.Equal(t, []BucketSpan{{Offset: -2, Length: 1}}, eh.Value.Negative.Spans)
+	assert.Equal(t, []uint64{2}, eh.Value.Negative.Counts)
+
+	assert.Error(t, eh.SetZeroBucket(1, -1))
+	assert.Error(t, eh.SetZeroBucket(1, math.NaN()))
+	assert.EqualError(t, eh.SetZeroBucket(1, math.Inf(1)), "zero threshold (+Inf) must be a non-negative finite number")
+	assert.NoError(t, eh.SetZeroBucket(1, 0))
+	assert.NoError(t, eh.SetZeroBucket(1, 0.001))
+}
+
+func Test_Metric_ExponentialHistogramMerge(t *testing.T) {
+	eh1, err := NewExponentialHistogram(now, "some-histogram", 1, 4, 10)
+	require.NoError(t, err)
+	eh1.AddPositiveBucket(1, 1)
+	eh1.AddPositiveBucket(2, 1)
+	eh1.AddPositiveBucket(3, 2)
+
+	eh2, err := NewExponentialHistogram(now, "some-histogram", 0, 3, 5)
+	require.NoError(t, err)
+	eh2.AddPositiveBucket(1, 2)
+	eh2.AddNegativeBucket(3, 1)
+	require.NoError(t, eh2.SetZeroBucket(1, 1))
+
+	require.NoError(t, eh1.Merge(eh2))
+
+	assert.Equal(t, int32(0), eh1.Value.Scale)
+	assert.Equal(t, uint64(7), *eh1.Value.SampleCount)
+	assert.Equal(t, float64(15), *eh1.Value.SampleSum)
+	// buckets 1 and 2 at scale 1 become bucket 1 at scale 0, bucket 3 becomes bucket 2.
+	// Bucket 1 at scale 0 has 2 as upper bound, so it stays out of the zero bucket.
+	assert.Equal(t, []BucketSpan{{Offset: 1, Length: 2}}, eh1.Value.Positive.Spans)
+	assert.Equal(t, []uint64{4, 2}, eh1.Value.Positive.Counts)
+	assert.Equal(t, []uint64{1}, eh1.Value.Negative.Counts)
+	assert.Equal(t, uint64(1), eh1.Value.ZeroCount)
+	assert.Equal(t, float64(1), eh1.Value.ZeroThreshold)
+}
+
+func Test_Metric_ExponentialHistogramMergeWidensZeroBucket(t *testing.T) {
+	eh1, err := NewExponentialHistogram(now, "some-histogram", 0, 2, 1.5)
+	require.NoError(t, err)
+	eh1.AddPositiveBucket(0, 1)
+	eh1.AddPositiveBucket(-1, 1)
+
+	eh2, err := NewExponentialHistogram(now, "some-histogram", 0, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, eh2.SetZeroBucket(0, 1))
+
+	require.NoError(t, eh1.Merge(eh2))
+
+	assert.Nil(t, eh1.Value.Positive)
+	assert.Equal(t, uint64(2), eh1.Value.ZeroCount)
+}
+
+func Test_Metric_CannotMergeDifferentExponentialHistograms(t *testing.T) {
+	eh1, _ := NewExponentialHistogram(now, "some-histogram", 0, 0, 0)
+	eh2, _ := NewExponentialHistogram(now, "other-histogram", 0, 0, 0)
+
+	assert.Error(t, eh1.Merge(eh2))
+	assert.Error(t, eh1.Merge(nil))
+}
+
+func Test_Metric_ExponentialHistogramJSON(t *testing.T) {
+	eh, err := NewExponentialHistogram(time.Unix(10000000, 0), "some-histogram", 2, 4, 10)
+	require.NoError(t, err)
+	_ = eh.AddDimension("host", "a")
+	eh.AddPositiveBucket(3, 1)
+	eh.AddPositiveBucket(5, 2)
+	require.NoError(t, eh.SetZeroBucket(1, 0.5))
+
+	out, err := json.Marshal(eh)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"timestamp": 10000000,
+		"name": "some-histogram",
+		"type": "exponential-histogram",
+		"attributes": {"host": "a"},
+		"value": {
+			"sample_count": 4,
+			"sample_sum": 10,
+			"scale": 2,
+			"zero_count": 1,
+			"zero_threshold": 0.5,
+			"positive": {
+				"spans": [{"offset": 3, "length": 1}, {"offset": 1, "length": 1}],
+				"counts": [1, 2]
+			}
+		}
+	}`, string(out))
+}
@@ -1,10 +1,14 @@
 package metric
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
+	"sort"
 	"time"
 
 	err "github.com/newrelic/infra-integrations-sdk/v4/data/errors"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 )
 
 // Dimensions stores the metric dimensions
@@ -16,8 +20,28 @@ type Metrics []Metric
 // Metric is the common interface for all metric types
 type Metric interface {
 	AddDimension(key string, value string) error
+	AddTypedDimension(key string, value interface{}) error
 	Dimension(key string) string
+	TypedDimension(key string) interface{}
 	GetDimensions() Dimensions
+	GetName() string
+	GetType() SourceType
+	GetTimestamp() time.Time
+	// Clone returns a deep copy of the metric, so it can be modified and added to another entity.
+	Clone() Metric
+}
+
+// NumericMetric is implemented by the metrics holding a single numeric value:
+// gauge, count, cumulative count, rate and cumulative rate.
+type NumericMetric interface {
+	Metric
+	GetValue() float64
+}
+
+// SummaryMetric is implemented by the metrics of type summary.
+type SummaryMetric interface {
+	Metric
+	GetValue() SummaryValue
 }
 
 type metricBase struct {
@@ -25,6 +49,32 @@ type metricBase struct {
 	Name       string     `json:"name"`
 	Type       string     `json:"type"`
 	Dimensions Dimensions `json:"attributes"`
+	// dimensions with numeric or boolean values, merged into the attributes when serialized
+	typedDimensions map[string]interface{}
+	// precision of the timestamp, see the precision package
+	precision time.Duration
+}
+
+// newMetricBase creates the fields common to all metrics, with the timestamp in the current precision.
+func newMetricBase(timestamp time.Time, name string, sourceType SourceType) metricBase {
+	// the precision is read once, so the timestamp and its precision always match
+	p := precision.Get()
+	return metricBase{
+		Timestamp:  precision.Timestamp(timestamp, p),
+		precision:  p,
+		Name:       name,
+		Type:       SourcesTypeToName[sourceType],
+		Dimensions: Dimensions{},
+	}
+}
+
+// baseJSON is the JSON representation of the fields common to all metrics, where the typed dimensions are
+// merged into the attributes.
+type baseJSON struct {
+	Timestamp  int64                  `json:"timestamp"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
 // gauge is a metric of type gauge
@@ -43,10 +93,12 @@ type count struct {
 // summary is a metric of type summary.
 type summary struct {
 	metricBase
-	Value summaryValue `json:"value"`
+	Value SummaryValue `json:"value"`
 }
 
-type summaryValue struct {
+// SummaryValue represents the Value type for a summary. Values that are not a finite number are nil, see
+// NonFinitePolicy.
+type SummaryValue struct {
 	Count   *float64 `json:"count"`
 	Average *float64 `json:"average"`
 	Sum     *float64 `json:"sum"`
@@ -59,10 +111,20 @@ type bucket struct {
 	UpperBound      *float64 `json:"upper_bound,omitempty"`
 }
 
+// Normalizer is implemented by the metrics that can be validated and put in a canonical form before being
+// published.
+type Normalizer interface {
+	Normalize() error
+}
+
 // PrometheusHistogram represents a Prometheus histogram
 type PrometheusHistogram struct {
 	metricBase
 	Value PrometheusHistogramValue `json:"value,omitempty"`
+	// infCount holds the cumulative count of the +Inf bucket, which is not serialized
+	infCount *uint64
+	// invalidBounds counts the buckets discarded because of a NaN or -Inf upper bound
+	invalidBounds int
 }
 
 // PrometheusHistogramValue represents the Value type for a Prometheus histogram.
@@ -112,13 +174,8 @@ func NewGauge(timestamp time.Time, name string, value float64) (Metric, error) {
 	}
 
 	return &gauge{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[GAUGE],
-			Dimensions: Dimensions{},
-		},
-		Value: value,
+		metricBase: newMetricBase(timestamp, name, GAUGE),
+		Value:      value,
 	}, nil
 }
 
@@ -131,13 +188,8 @@ func NewCount(timestamp time.Time, name string, value float64) (Metric, error) {
 		return nil, err.ParameterCannotBeNegative("value", value)
 	}
 	return &count{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[COUNT],
-			Dimensions: Dimensions{},
-		},
-		Value: value,
+		metricBase: newMetricBase(timestamp, name, COUNT),
+		Value:      value,
 	}, nil
 }
 
@@ -152,13 +204,8 @@ func NewSummary(timestamp time.Time, name string, count float64, average float64
 	}
 
 	return &summary{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[SUMMARY],
-			Dimensions: Dimensions{},
-		},
-		Value: summaryValue{
+		metricBase: newMetricBase(timestamp, name, SUMMARY),
+		Value: SummaryValue{
 			Count:   asFloatPtr(count),
 			Average: asFloatPtr(average),
 			Sum:     asFloatPtr(sum),
@@ -177,13 +224,8 @@ func NewCumulativeCount(timestamp time.Time, name string, value float64) (Metric
 		return nil, err.ParameterCannotBeNegative("value", value)
 	}
 	return &cumulativeCount{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[CUMULATIVE_COUNT],
-			Dimensions: Dimensions{},
-		},
-		Value: value,
+		metricBase: newMetricBase(timestamp, name, CUMULATIVE_COUNT),
+		Value:      value,
 	}, nil
 }
 
@@ -194,13 +236,8 @@ func NewRate(timestamp time.Time, name string, value float64) (Metric, error) {
 	}
 
 	return &rate{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[RATE],
-			Dimensions: Dimensions{},
-		},
-		Value: value,
+		metricBase: newMetricBase(timestamp, name, RATE),
+		Value:      value,
 	}, nil
 
 }
@@ -212,25 +249,15 @@ func NewCumulativeRate(timestamp time.Time, name string, value float64) (Metric,
 	}
 
 	return &cumulativeRate{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[CUMULATIVE_RATE],
-			Dimensions: Dimensions{},
-		},
-		Value: value,
+		metricBase: newMetricBase(timestamp, name, CUMULATIVE_RATE),
+		Value:      value,
 	}, nil
 }
 
 // NewPrometheusHistogram creates a new metric structurally similar to a Prometheus histogram
 func NewPrometheusHistogram(timestamp time.Time, name string, sampleCount uint64, sampleSum float64) (*PrometheusHistogram, error) {
 	return &PrometheusHistogram{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[PROMETHEUS_HISTOGRAM],
-			Dimensions: Dimensions{},
-		},
+		metricBase: newMetricBase(timestamp, name, PROMETHEUS_HISTOGRAM),
 		Value: PrometheusHistogramValue{
 			SampleCount: &sampleCount,
 			SampleSum:   asFloatPtr(sampleSum),
@@ -240,10 +267,15 @@ func NewPrometheusHistogram(timestamp time.Time, name string, sampleCount uint64
 
 // AddBucket adds a new bucket to the histogram.
 // Note that no attempt is made to keep buckets ordered, it's on the caller to guarantee the buckets are added
-// in the correct order.
+// in the correct order or to call Normalize afterwards.
 func (ph *PrometheusHistogram) AddBucket(cumulativeCount uint64, upperBound float64) {
-	// ignore +Inf buckets
-	if math.IsNaN(upperBound) || math.IsInf(upperBound, 0) {
+	// +Inf buckets are not serialized, as their count must match the sample count
+	if math.IsInf(upperBound, 1) {
+		ph.infCount = &cumulativeCount
+		return
+	}
+	if math.IsNaN(upperBound) || math.IsInf(upperBound, -1) {
+		ph.invalidBounds++
 		return
 	}
 	ph.Value.Buckets = append(ph.Value.Buckets, &bucket{
@@ -252,15 +284,52 @@ func (ph *PrometheusHistogram) AddBucket(cumulativeCount uint64, upperBound floa
 	})
 }
 
+// Normalize sorts the histogram buckets by upper bound and validates them. An error is returned when
+// buckets with invalid or duplicated upper bounds were added, when cumulative counts decrease as upper bounds
+// increase, or when the +Inf bucket count or the last bucket count doesn't match the sample count.
+// If a +Inf bucket was added and the sample count is missing, it's taken from the +Inf bucket.
+func (ph *PrometheusHistogram) Normalize() error {
+	if ph.invalidBounds > 0 {
+		return fmt.Errorf("histogram %s has %d buckets with invalid upper bound", ph.Name, ph.invalidBounds)
+	}
+
+	buckets := ph.Value.Buckets
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return *buckets[i].UpperBound < *buckets[j].UpperBound
+	})
+	for i := 1; i < len(buckets); i++ {
+		if *buckets[i].UpperBound == *buckets[i-1].UpperBound {
+			return fmt.Errorf("histogram %s has duplicated buckets with upper bound %v", ph.Name, *buckets[i].UpperBound)
+		}
+		if *buckets[i].CumulativeCount < *buckets[i-1].CumulativeCount {
+			return fmt.Errorf("histogram %s cumulative count decreases from %d to %d at upper bound %v",
+				ph.Name, *buckets[i-1].CumulativeCount, *buckets[i].CumulativeCount, *buckets[i].UpperBound)
+		}
+	}
+
+	if ph.infCount != nil {
+		if ph.Value.SampleCount == nil {
+			sampleCount := *ph.infCount
+			ph.Value.SampleCount = &sampleCount
+		} else if *ph.Value.SampleCount != *ph.infCount {
+			return fmt.Errorf("histogram %s +Inf bucket count (%d) doesn't match its sample count (%d)",
+				ph.Name, *ph.infCount, *ph.Value.SampleCount)
+		}
+	}
+
+	if len(buckets) > 0 && ph.Value.SampleCount != nil {
+		if last := *buckets[len(buckets)-1].CumulativeCount; last > *ph.Value.SampleCount {
+			return fmt.Errorf("histogram %s last bucket count (%d) is greater than its sample count (%d)",
+				ph.Name, last, *ph.Value.SampleCount)
+		}
+	}
+	return nil
+}
+
 // NewPrometheusSummary creates a new metric structurally similar to a Prometheus summary
 func NewPrometheusSummary(timestamp time.Time, name string, sampleCount uint64, sampleSum float64) (*PrometheusSummary, error) {
 	return &PrometheusSummary{
-		metricBase: metricBase{
-			Timestamp:  timestamp.Unix(),
-			Name:       name,
-			Type:       SourcesTypeToName[PROMETHEUS_SUMMARY],
-			Dimensions: Dimensions{},
-		},
+		metricBase: newMetricBase(timestamp, name, PROMETHEUS_SUMMARY),
 		Value: PrometheusSummaryValue{
 			SampleCount: &sampleCount,
 			SampleSum:   asFloatPtr(sampleSum),
@@ -268,10 +337,11 @@ func NewPrometheusSummary(timestamp time.Time, name string, sampleCount uint64,
 	}, nil
 }
 
-// AddQuantile adds a new quantile to the summary.
+// AddQuantile adds a new quantile to the summary. Quantiles that are not a finite number (NaN or infinite) are
+// ignored, while values that are not a finite number are kept and handled according to the NonFinitePolicy in use.
 func (ps *PrometheusSummary) AddQuantile(quant float64, value float64) {
 	// ignore invalid quantiles
-	if math.IsNaN(quant) || math.IsNaN(value) {
+	if math.IsNaN(quant) || math.IsInf(quant, 0) {
 		return
 	}
 	ps.Value.Quantiles = append(ps.Value.Quantiles, &quantile{
@@ -280,6 +350,27 @@ func (ps *PrometheusSummary) AddQuantile(quant float64, value float64) {
 	})
 }
 
+// Normalize sorts the summary quantiles and validates them. An error is returned when any quantile
+// is out of the [0, 1] range or is duplicated.
+func (ps *PrometheusSummary) Normalize() error {
+	quantiles := ps.Value.Quantiles
+	for _, q := range quantiles {
+		if q.Quantile == nil || *q.Quantile < 0 || *q.Quantile > 1 {
+			return fmt.Errorf("summary %s has quantiles out of the [0, 1] range", ps.Name)
+		}
+	}
+
+	sort.SliceStable(quantiles, func(i, j int) bool {
+		return *quantiles[i].Quantile < *quantiles[j].Quantile
+	})
+	for i := 1; i < len(quantiles); i++ {
+		if *quantiles[i].Quantile == *quantiles[i-1].Quantile {
+			return fmt.Errorf("summary %s has duplicated quantile %v", ps.Name, *quantiles[i].Quantile)
+		}
+	}
+	return nil
+}
+
 // AddDimension adds a dimension to the metric instance
 func (m *metricBase) AddDimension(key string, value string) error {
 	if len(key) == 0 {
@@ -287,19 +378,328 @@ func (m *metricBase) AddDimension(key string, value string) error {
 	}
 
 	m.Dimensions[key] = value
+	delete(m.typedDimensions, key)
+	return nil
+}
+
+// AddTypedDimension adds a dimension with a numeric, boolean or string value to the metric instance.
+// See AttributeValue for the supported types. String values are added as AddDimension does, while the rest
+// are only returned by TypedDimension, or by Dimension formatted as strings.
+func (m *metricBase) AddTypedDimension(key string, value interface{}) error {
+	if len(key) == 0 {
+		return err.ParameterCannotBeEmpty("name")
+	}
+
+	v, e := AttributeValue(value)
+	if e != nil {
+		return fmt.Errorf("invalid dimension %s: %s", key, e)
+	}
+	if str, ok := v.(string); ok {
+		return m.AddDimension(key, str)
+	}
+
+	if m.typedDimensions == nil {
+		m.typedDimensions = map[string]interface{}{}
+	}
+	m.typedDimensions[key] = v
+	delete(m.Dimensions, key)
 	return nil
 }
 
-// Dimension returns an dimension by key
+// Dimension returns an dimension by key. Typed dimensions are formatted as strings.
 func (m *metricBase) Dimension(key string) string {
+	if v, ok := m.typedDimensions[key]; ok {
+		return fmt.Sprint(v)
+	}
 	return m.Dimensions[key]
 }
 
-// GetDimensions gets all the dimensions
+// TypedDimension returns a dimension by key, keeping its type. Returns nil if the dimension doesn't exist.
+func (m *metricBase) TypedDimension(key string) interface{} {
+	if v, ok := m.typedDimensions[key]; ok {
+		return v
+	}
+	if v, ok := m.Dimensions[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// GetDimensions gets all the dimensions with string values, see TypedDimension for the rest.
 func (m *metricBase) GetDimensions() Dimensions {
 	return m.Dimensions
 }
 
+// attributes returns all the dimensions, with string or typed values.
+func (m *metricBase) attributes() map[string]interface{} {
+	if m.Dimensions == nil && len(m.typedDimensions) == 0 {
+		return nil
+	}
+	attributes := make(map[string]interface{}, len(m.Dimensions)+len(m.typedDimensions))
+	for k, v := range m.Dimensions {
+		attributes[k] = v
+	}
+	for k, v := range m.typedDimensions {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+func (m *metricBase) baseJSON() baseJSON {
+	return baseJSON{
+		Timestamp:  m.Timestamp,
+		Name:       m.Name,
+		Type:       m.Type,
+		Attributes: m.attributes(),
+	}
+}
+
+// GetName returns the metric name
+func (m *metricBase) GetName() string {
+	return m.Name
+}
+
+// GetType returns the metric source type
+func (m *metricBase) GetType() SourceType {
+	return SourcesNameToType[m.Type]
+}
+
+// GetTimestamp returns the metric timestamp
+func (m *metricBase) GetTimestamp() time.Time {
+	return precision.Time(m.Timestamp, m.precision)
+}
+
+// GetValue returns the gauge value
+func (g *gauge) GetValue() float64 {
+	return g.Value
+}
+
+// Clone returns a deep copy of the gauge
+func (g *gauge) Clone() Metric {
+	c := *g
+	c.metricBase = g.metricBase.clone()
+	return &c
+}
+
+// GetValue returns the count value
+func (c *count) GetValue() float64 {
+	return c.Value
+}
+
+// Clone returns a deep copy of the count
+func (c *count) Clone() Metric {
+	cl := *c
+	cl.metricBase = c.metricBase.clone()
+	return &cl
+}
+
+// GetValue returns the cumulative count value
+func (c *cumulativeCount) GetValue() float64 {
+	return c.Value
+}
+
+// Clone returns a deep copy of the cumulative count
+func (c *cumulativeCount) Clone() Metric {
+	cl := *c
+	cl.metricBase = c.metricBase.clone()
+	return &cl
+}
+
+// GetValue returns the rate value
+func (r *rate) GetValue() float64 {
+	return r.Value
+}
+
+// Clone returns a deep copy of the rate
+func (r *rate) Clone() Metric {
+	c := *r
+	c.metricBase = r.metricBase.clone()
+	return &c
+}
+
+// GetValue returns the cumulative rate value
+func (r *cumulativeRate) GetValue() float64 {
+	return r.Value
+}
+
+// Clone returns a deep copy of the cumulative rate
+func (r *cumulativeRate) Clone() Metric {
+	c := *r
+	c.metricBase = r.metricBase.clone()
+	return &c
+}
+
+// MarshalJSON serializes the summary, fulfilling Marshaler interface.
+func (s *summary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		baseJSON
+		Value SummaryValue `json:"value"`
+	}{s.baseJSON(), s.Value})
+}
+
+// GetValue returns the summary value
+func (s *summary) GetValue() SummaryValue {
+	return s.Value
+}
+
+// Clone returns a deep copy of the summary
+func (s *summary) Clone() Metric {
+	return &summary{
+		metricBase: s.metricBase.clone(),
+		Value: SummaryValue{
+			Count:   cloneFloatPtr(s.Value.Count),
+			Average: cloneFloatPtr(s.Value.Average),
+			Sum:     cloneFloatPtr(s.Value.Sum),
+			Min:     cloneFloatPtr(s.Value.Min),
+			Max:     cloneFloatPtr(s.Value.Max),
+		},
+	}
+}
+
+// MarshalJSON serializes the Prometheus histogram, fulfilling Marshaler interface.
+func (ph *PrometheusHistogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		baseJSON
+		Value PrometheusHistogramValue `json:"value,omitempty"`
+	}{ph.baseJSON(), ph.Value})
+}
+
+// GetValue returns the Prometheus histogram value
+func (ph *PrometheusHistogram) GetValue() PrometheusHistogramValue {
+	return ph.Value
+}
+
+// Clone returns a deep copy of the Prometheus histogram
+func (ph *PrometheusHistogram) Clone() Metric {
+	c := &PrometheusHistogram{
+		metricBase: ph.metricBase.clone(),
+		Value: PrometheusHistogramValue{
+			SampleCount: cloneUintPtr(ph.Value.SampleCount),
+			SampleSum:   cloneFloatPtr(ph.Value.SampleSum),
+		},
+		infCount:      cloneUintPtr(ph.infCount),
+		invalidBounds: ph.invalidBounds,
+	}
+	for _, b := range ph.Value.Buckets {
+		c.Value.Buckets = append(c.Value.Buckets, &bucket{
+			CumulativeCount: cloneUintPtr(b.CumulativeCount),
+			UpperBound:      cloneFloatPtr(b.UpperBound),
+		})
+	}
+	return c
+}
+
+// MarshalJSON serializes the Prometheus summary, fulfilling Marshaler interface.
+func (ps *PrometheusSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		baseJSON
+		Value PrometheusSummaryValue `json:"value,omitempty"`
+	}{ps.baseJSON(), ps.Value})
+}
+
+// GetValue returns the Prometheus summary value
+func (ps *PrometheusSummary) GetValue() PrometheusSummaryValue {
+	return ps.Value
+}
+
+// Clone returns a deep copy of the Prometheus summary
+func (ps *PrometheusSummary) Clone() Metric {
+	c := &PrometheusSummary{
+		metricBase: ps.metricBase.clone(),
+		Value: PrometheusSummaryValue{
+			SampleCount: cloneUintPtr(ps.Value.SampleCount),
+			SampleSum:   cloneFloatPtr(ps.Value.SampleSum),
+		},
+	}
+	for _, q := range ps.Value.Quantiles {
+		c.Value.Quantiles = append(c.Value.Quantiles, &quantile{
+			Quantile: cloneFloatPtr(q.Quantile),
+			Value:    cloneFloatPtr(q.Value),
+		})
+	}
+	return c
+}
+
+// AttributeValue validates a dimension or attribute value and converts it into one of the supported
+// types: string, int64, float64 or bool. Any integer type is converted into int64 and float32 into float64.
+// An error is returned for any other type, for NaN or infinite floats and for unsigned values overflowing int64.
+func AttributeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string, bool, int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint:
+		return uintAttributeValue(uint64(v))
+	case uint64:
+		return uintAttributeValue(v)
+	case float32:
+		return floatAttributeValue(float64(v))
+	case float64:
+		return floatAttributeValue(v)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+func uintAttributeValue(value uint64) (interface{}, error) {
+	if value > math.MaxInt64 {
+		return nil, fmt.Errorf("value (%d) overflows int64", value)
+	}
+	return int64(value), nil
+}
+
+func floatAttributeValue(value float64) (interface{}, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("value (%v) is not a finite number", value)
+	}
+	return value, nil
+}
+
+func (m *metricBase) clone() metricBase {
+	c := *m
+	c.Dimensions = make(Dimensions, len(m.Dimensions))
+	for k, v := range m.Dimensions {
+		c.Dimensions[k] = v
+	}
+	c.typedDimensions = nil
+	for k, v := range m.typedDimensions {
+		if c.typedDimensions == nil {
+			c.typedDimensions = make(map[string]interface{}, len(m.typedDimensions))
+		}
+		c.typedDimensions[k] = v
+	}
+	return c
+}
+
+func cloneFloatPtr(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
+
+func cloneUintPtr(value *uint64) *uint64 {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
+
 func asFloatPtr(value float64) *float64 {
 	if math.IsNaN(value) || math.IsInf(value, 0) {
 		return nil
@@ -1,11 +1,14 @@
 package metric
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 )
 
 // growing time for tests to avoid errors generated by store avoiding samples too close in time
@@ -228,4 +231,214 @@ func Test_Metric_PrometheusSummaryAddQuantile(t *testing.T) {
 	// sampleSum is the sum of all "observed" values
 	assert.Equal(t, float64(3), *ps.Value.SampleSum)
 	assert.Len(t, ps.Value.Quantiles, 2)
+
+	// non-finite quantiles are ignored, while non-finite values are kept
+	ps.AddQuantile(math.NaN(), 1)
+	ps.AddQuantile(math.Inf(1), 1)
+	ps.AddQuantile(math.Inf(-1), 1)
+	assert.Len(t, ps.Value.Quantiles, 2)
+	ps.AddQuantile(0.99, math.Inf(1))
+	assert.Len(t, ps.Value.Quantiles, 3)
+	assert.Nil(t, ps.Value.Quantiles[2].Value)
+}
+
+func Test_Metric_PrometheusHistogramNormalize(t *testing.T) {
+	ph, _ := NewPrometheusHistogram(now, "some-histogram", 3, 6)
+	ph.AddBucket(2, 2)
+	ph.AddBucket(3, math.Inf(1))
+	ph.AddBucket(1, 1)
+
+	assert.NoError(t, ph.Normalize())
+	assert.Len(t, ph.Value.Buckets, 2)
+	assert.Equal(t, float64(1), *ph.Value.Buckets[0].UpperBound)
+	assert.Equal(t, float64(2), *ph.Value.Buckets[1].UpperBound)
+}
+
+func Test_Metric_PrometheusHistogramNormalizeErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		buckets [][2]float64 // cumulative count, upper bound
+	}{
+		{"non monotonic", [][2]float64{{2, 1}, {1, 2}}},
+		{"duplicated bound", [][2]float64{{1, 1}, {2, 1}}},
+		{"NaN bound", [][2]float64{{1, math.NaN()}}},
+		{"+Inf mismatch", [][2]float64{{1, 1}, {2, math.Inf(1)}}},
+		{"last bucket greater than count", [][2]float64{{4, 1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ph, _ := NewPrometheusHistogram(now, "some-histogram", 3, 6)
+			for _, b := range tc.buckets {
+				ph.AddBucket(uint64(b[0]), b[1])
+			}
+			assert.Error(t, ph.Normalize())
+		})
+	}
+}
+
+func Test_Metric_PrometheusSummaryNormalize(t *testing.T) {
+	ps, _ := NewPrometheusSummary(now, "some-summary", 2, 3)
+	ps.AddQuantile(0.9, 2)
+	ps.AddQuantile(0.5, 1)
+
+	assert.NoError(t, ps.Normalize())
+	assert.Equal(t, 0.5, *ps.Value.Quantiles[0].Quantile)
+	assert.Equal(t, 0.9, *ps.Value.Quantiles[1].Quantile)
+
+	ps.AddQuantile(1.5, 3)
+	assert.Error(t, ps.Normalize())
+
+	ps, _ = NewPrometheusSummary(now, "some-summary", 2, 3)
+	ps.AddQuantile(0.5, 1)
+	ps.AddQuantile(0.5, 2)
+	assert.Error(t, ps.Normalize())
+}
+
+func Test_Metric_Accessors(t *testing.T) {
+	ts := time.Unix(10000000, 0)
+	g, _ := NewGauge(ts, "gauge", 1)
+	c, _ := NewCount(ts, "count", 2)
+	cc, _ := NewCumulativeCount(ts, "cumulative-count", 3)
+	r, _ := NewRate(ts, "rate", 4)
+	cr, _ := NewCumulativeRate(ts, "cumulative-rate", 5)
+
+	testCases := []struct {
+		metric     Metric
+		name       string
+		sourceType SourceType
+		value      float64
+	}{
+		{g, "gauge", GAUGE, 1},
+		{c, "count", COUNT, 2},
+		{cc, "cumulative-count", CUMULATIVE_COUNT, 3},
+		{r, "rate", RATE, 4},
+		{cr, "cumulative-rate", CUMULATIVE_RATE, 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.name, tc.metric.GetName())
+			assert.Equal(t, tc.sourceType, tc.metric.GetType())
+			assert.Equal(t, ts, tc.metric.GetTimestamp())
+
+			n, ok := tc.metric.(NumericMetric)
+			assert.True(t, ok)
+			assert.Equal(t, tc.value, n.GetValue())
+		})
+	}
+}
+
+func Test_Metric_SummaryAccessors(t *testing.T) {
+	s, _ := NewSummary(now, "summary", 1, math.NaN(), 10, 1, 10)
+
+	assert.Equal(t, SUMMARY, s.GetType())
+	sm, ok := s.(SummaryMetric)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1), *sm.GetValue().Count)
+	assert.Nil(t, sm.GetValue().Average)
+	assert.Equal(t, float64(10), *sm.GetValue().Max)
+}
+
+func Test_Metric_CloneIsIndependent(t *testing.T) {
+	g, _ := NewGauge(now, "gauge", 1)
+	_ = g.AddDimension("a", "1")
+
+	c := g.Clone()
+	_ = c.AddDimension("b", "2")
+
+	assert.Equal(t, Dimensions{"a": "1"}, g.GetDimensions())
+	assert.Equal(t, Dimensions{"a": "1", "b": "2"}, c.GetDimensions())
+	assert.Equal(t, float64(1), c.(NumericMetric).GetValue())
+
+	ph, _ := NewPrometheusHistogram(now, "histogram", 2, 3)
+	ph.AddBucket(1, 1)
+	phc := ph.Clone().(*PrometheusHistogram)
+	*phc.Value.Buckets[0].CumulativeCount = 2
+	*phc.Value.SampleCount = 3
+	assert.Equal(t, uint64(1), *ph.GetValue().Buckets[0].CumulativeCount)
+	assert.Equal(t, uint64(2), *ph.GetValue().SampleCount)
+
+	ps, _ := NewPrometheusSummary(now, "summary", 2, 3)
+	ps.AddQuantile(0.5, 1)
+	psc := ps.Clone().(*PrometheusSummary)
+	*psc.Value.Quantiles[0].Value = 2
+	assert.Equal(t, float64(1), *ps.GetValue().Quantiles[0].Value)
+
+	eh, _ := NewExponentialHistogram(now, "exponential", 0, 1, 1)
+	eh.AddPositiveBucket(1, 1)
+	ehc := eh.Clone().(*ExponentialHistogram)
+	ehc.AddPositiveBucket(1, 1)
+	assert.Equal(t, []uint64{1}, eh.GetValue().Positive.Counts)
+	assert.Equal(t, []uint64{2}, ehc.GetValue().Positive.Counts)
+}
+
+func Test_Metric_TimestampPrecision(t *testing.T) {
+	defer func() { _ = precision.Set(time.Second) }()
+	ts := time.Unix(10000000, 123456789)
+
+	g, _ := NewGauge(ts, "gauge", 1)
+	assert.Equal(t, int64(10000000), g.(*gauge).Timestamp)
+	assert.Equal(t, time.Unix(10000000, 0), g.GetTimestamp())
+
+	assert.NoError(t, precision.Set(time.Millisecond))
+	g, _ = NewGauge(ts, "gauge", 1)
+	assert.Equal(t, int64(10000000123), g.(*gauge).Timestamp)
+	assert.Equal(t, time.Unix(10000000, 123000000), g.GetTimestamp())
+}
+
+func Test_Metric_AddTypedDimension(t *testing.T) {
+	g, _ := NewGauge(now, "gauge", 1)
+
+	assert.NoError(t, g.AddTypedDimension("shard", 7))
+	assert.NoError(t, g.AddTypedDimension("ratio", 0.25))
+	assert.NoError(t, g.AddTypedDimension("leader", false))
+	assert.NoError(t, g.AddTypedDimension("name", "db"))
+
+	assert.Equal(t, int64(7), g.TypedDimension("shard"))
+	assert.Equal(t, "7", g.Dimension("shard"))
+	assert.Equal(t, 0.25, g.TypedDimension("ratio"))
+	assert.Equal(t, "false", g.Dimension("leader"))
+	assert.Equal(t, "db", g.Dimension("name"))
+	assert.Nil(t, g.TypedDimension("missing"))
+	assert.Equal(t, "", g.Dimension("missing"))
+	assert.Equal(t, Dimensions{"name": "db"}, g.GetDimensions())
+
+	assert.NoError(t, g.AddDimension("shard", "primary"))
+	assert.Equal(t, "primary", g.TypedDimension("shard"), "string dimension replaces the typed one")
+}
+
+func Test_Metric_TypedDimensionsAreSerializedAsAttributes(t *testing.T) {
+	for _, m := range []Metric{
+		func() Metric { g, _ := NewGauge(now, "gauge", 1); return g }(),
+		func() Metric { s, _ := NewSummary(now, "summary", 1, 1, 1, 1, 1); return s }(),
+		func() Metric { h, _ := NewPrometheusHistogram(now, "histogram", 1, 1); return h }(),
+		func() Metric { s, _ := NewPrometheusSummary(now, "psummary", 1, 1); return s }(),
+		func() Metric { h, _ := NewExponentialHistogram(now, "exponential", 0, 1, 1); return h }(),
+	} {
+		assert.NoError(t, m.AddDimension("name", "db"))
+		assert.NoError(t, m.AddTypedDimension("shard", 7))
+
+		out, err := json.Marshal(m.Clone())
+		assert.NoError(t, err)
+
+		var decoded struct {
+			Attributes map[string]interface{} `json:"attributes"`
+			Value      interface{}            `json:"value"`
+		}
+		assert.NoError(t, json.Unmarshal(out, &decoded))
+		assert.Equal(t, map[string]interface{}{"name": "db", "shard": float64(7)}, decoded.Attributes, string(out))
+		assert.NotNil(t, decoded.Value, string(out))
+	}
+}
+
+func Test_Metric_CannotAddInvalidTypedDimension(t *testing.T) {
+	g, _ := NewGauge(now, "gauge", 1)
+
+	assert.Error(t, g.AddTypedDimension("", 1))
+	assert.Error(t, g.AddTypedDimension("nan", math.NaN()))
+	assert.Error(t, g.AddTypedDimension("inf", math.Inf(-1)))
+	assert.Error(t, g.AddTypedDimension("overflow", uint64(math.MaxUint64)))
+	assert.Error(t, g.AddTypedDimension("map", map[string]string{}))
+	assert.Error(t, g.AddTypedDimension("nil", nil))
+	assert.Len(t, g.GetDimensions(), 0)
 }
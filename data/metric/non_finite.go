@@ -0,0 +1,215 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// NonFinitePolicy defines how the metrics holding NaN or infinite values are handled before being published.
+type NonFinitePolicy int
+
+// Non-finite value policies
+const (
+	// NonFiniteNull publishes non-finite values as null. This is the default policy.
+	NonFiniteNull NonFinitePolicy = iota
+	// NonFiniteDrop discards the metrics holding non-finite values.
+	NonFiniteDrop
+	// NonFiniteZero replaces non-finite values by zero.
+	NonFiniteZero
+	// NonFiniteError rejects the metrics holding non-finite values with an error.
+	NonFiniteError
+)
+
+var nonFinitePolicyNames = map[NonFinitePolicy]string{
+	NonFiniteNull:  "null",
+	NonFiniteDrop:  "drop",
+	NonFiniteZero:  "zero",
+	NonFiniteError: "error",
+}
+
+// String fulfills stringer interface, returning empty string on invalid policies.
+func (p NonFinitePolicy) String() string {
+	return nonFinitePolicyNames[p]
+}
+
+// nonFiniteHolder is implemented by the metrics that can hold NaN or infinite values.
+type nonFiniteHolder interface {
+	// nonFinite returns the names of the values that are not a finite number.
+	nonFinite() []string
+	// zeroNonFinite replaces the values that are not a finite number by zero.
+	zeroNonFinite()
+}
+
+// Apply applies the policy to the metric. It returns false if the metric must be discarded, along with an error
+// listing its non-finite values for the NonFiniteError policy.
+func (p NonFinitePolicy) Apply(m Metric) (bool, error) {
+	h, ok := m.(nonFiniteHolder)
+	if !ok {
+		return true, nil
+	}
+	values := h.nonFinite()
+	if len(values) == 0 {
+		return true, nil
+	}
+
+	switch p {
+	case NonFiniteDrop:
+		return false, nil
+	case NonFiniteZero:
+		h.zeroNonFinite()
+		return true, nil
+	case NonFiniteError:
+		return false, fmt.Errorf("metric %s has non-finite values: %s", m.GetName(), strings.Join(values, ", "))
+	default:
+		return true, nil
+	}
+}
+
+// numericJSON is the JSON representation of the metrics holding a single numeric value, which is null when
+// not finite, as JSON has no representation for NaN or infinite numbers.
+type numericJSON struct {
+	baseJSON
+	Value *float64 `json:"value"`
+}
+
+func marshalNumeric(base metricBase, value float64) ([]byte, error) {
+	return json.Marshal(numericJSON{baseJSON: base.baseJSON(), Value: asFloatPtr(value)})
+}
+
+func nonFiniteValue(value float64) []string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return []string{"value"}
+	}
+	return nil
+}
+
+func nonFinitePtrs(names []string, values ...*float64) []string {
+	var nonFinite []string
+	for n, v := range values {
+		if v == nil {
+			nonFinite = append(nonFinite, names[n])
+		}
+	}
+	return nonFinite
+}
+
+func zeroPtrs(values ...**float64) {
+	for _, v := range values {
+		if *v == nil {
+			*v = new(float64)
+		}
+	}
+}
+
+// MarshalJSON serializes the gauge, fulfilling Marshaler interface.
+func (g *gauge) MarshalJSON() ([]byte, error) {
+	return marshalNumeric(g.metricBase, g.Value)
+}
+
+func (g *gauge) nonFinite() []string {
+	return nonFiniteValue(g.Value)
+}
+
+func (g *gauge) zeroNonFinite() {
+	g.Value = 0
+}
+
+// MarshalJSON serializes the count, fulfilling Marshaler interface.
+func (c *count) MarshalJSON() ([]byte, error) {
+	return marshalNumeric(c.metricBase, c.Value)
+}
+
+func (c *count) nonFinite() []string {
+	return nonFiniteValue(c.Value)
+}
+
+func (c *count) zeroNonFinite() {
+	c.Value = 0
+}
+
+// MarshalJSON serializes the cumulative count, fulfilling Marshaler interface.
+func (c *cumulativeCount) MarshalJSON() ([]byte, error) {
+	return marshalNumeric(c.metricBase, c.Value)
+}
+
+func (c *cumulativeCount) nonFinite() []string {
+	return nonFiniteValue(c.Value)
+}
+
+func (c *cumulativeCount) zeroNonFinite() {
+	c.Value = 0
+}
+
+// MarshalJSON serializes the rate, fulfilling Marshaler interface.
+func (r *rate) MarshalJSON() ([]byte, error) {
+	return marshalNumeric(r.metricBase, r.Value)
+}
+
+func (r *rate) nonFinite() []string {
+	return nonFiniteValue(r.Value)
+}
+
+func (r *rate) zeroNonFinite() {
+	r.Value = 0
+}
+
+// MarshalJSON serializes the cumulative rate, fulfilling Marshaler interface.
+func (r *cumulativeRate) MarshalJSON() ([]byte, error) {
+	return marshalNumeric(r.metricBase, r.Value)
+}
+
+func (r *cumulativeRate) nonFinite() []string {
+	return nonFiniteValue(r.Value)
+}
+
+func (r *cumulativeRate) zeroNonFinite() {
+	r.Value = 0
+}
+
+func (s *summary) nonFinite() []string {
+	v := s.Value
+	return nonFinitePtrs([]string{"count", "average", "sum", "min", "max"}, v.Count, v.Average, v.Sum, v.Min, v.Max)
+}
+
+func (s *summary) zeroNonFinite() {
+	zeroPtrs(&s.Value.Count, &s.Value.Average, &s.Value.Sum, &s.Value.Min, &s.Value.Max)
+}
+
+func (ph *PrometheusHistogram) nonFinite() []string {
+	return nonFinitePtrs([]string{"sample_sum"}, ph.Value.SampleSum)
+}
+
+func (ph *PrometheusHistogram) zeroNonFinite() {
+	zeroPtrs(&ph.Value.SampleSum)
+}
+
+func (ps *PrometheusSummary) nonFinite() []string {
+	nonFinite := nonFinitePtrs([]string{"sample_sum"}, ps.Value.SampleSum)
+	for _, q := range ps.Value.Quantiles {
+		if q.Value == nil && q.Quantile != nil {
+			nonFinite = append(nonFinite, fmt.Sprintf("quantile %v", *q.Quantile))
+		}
+	}
+	return nonFinite
+}
+
+func (ps *PrometheusSummary) zeroNonFinite() {
+	zeroPtrs(&ps.Value.SampleSum)
+	for _, q := range ps.Value.Quantiles {
+		zeroPtrs(&q.Value)
+	}
+}
+
+func (eh *ExponentialHistogram) nonFinite() []string {
+	return nonFinitePtrs([]string{"sample_sum", "zero_threshold"},
+		eh.Value.SampleSum, asFloatPtr(eh.Value.ZeroThreshold))
+}
+
+func (eh *ExponentialHistogram) zeroNonFinite() {
+	zeroPtrs(&eh.Value.SampleSum)
+	if asFloatPtr(eh.Value.ZeroThreshold) == nil {
+		eh.Value.ZeroThreshold = 0
+	}
+}
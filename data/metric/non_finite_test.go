@@ -0,0 +1,69 @@
+package metric
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NonFinite_NumericValuesAreSerializedAsNull(t *testing.T) {
+	g, err := NewGauge(now, "gauge", math.NaN())
+	require.NoError(t, err)
+	c, err := NewCumulativeRate(now, "rate", math.Inf(1))
+	require.NoError(t, err)
+
+	out, err := json.Marshal(Metrics{g, c})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"name":"gauge","type":"gauge","attributes":{},"value":null`)
+	assert.Contains(t, string(out), `"name":"rate","type":"cumulative-rate","attributes":{},"value":null`)
+}
+
+func Test_NonFinite_Policies(t *testing.T) {
+	newMetrics := func() Metrics {
+		g, _ := NewGauge(now, "gauge", math.NaN())
+		s, _ := NewSummary(now, "summary", 1, math.NaN(), 10, 1, math.Inf(1))
+		ps, _ := NewPrometheusSummary(now, "prometheus-summary", 1, 1)
+		ps.AddQuantile(0.5, math.Inf(-1))
+		finite, _ := NewCount(now, "count", 1)
+		return Metrics{g, s, ps, finite}
+	}
+
+	for _, m := range newMetrics() {
+		keep, err := NonFiniteNull.Apply(m)
+		assert.NoError(t, err)
+		assert.True(t, keep)
+	}
+
+	var kept []string
+	for _, m := range newMetrics() {
+		keep, err := NonFiniteDrop.Apply(m)
+		assert.NoError(t, err)
+		if keep {
+			kept = append(kept, m.GetName())
+		}
+	}
+	assert.Equal(t, []string{"count"}, kept)
+
+	metrics := newMetrics()
+	for _, m := range metrics {
+		keep, err := NonFiniteZero.Apply(m)
+		assert.NoError(t, err)
+		assert.True(t, keep)
+	}
+	assert.Equal(t, float64(0), metrics[0].(NumericMetric).GetValue())
+	assert.Equal(t, float64(0), *metrics[1].(SummaryMetric).GetValue().Average)
+	assert.Equal(t, float64(0), *metrics[1].(SummaryMetric).GetValue().Max)
+	assert.Equal(t, float64(0), *metrics[2].(*PrometheusSummary).GetValue().Quantiles[0].Value)
+
+	metrics = newMetrics()
+	_, err := NonFiniteError.Apply(metrics[1])
+	assert.EqualError(t, err, "metric summary has non-finite values: average, max")
+	_, err = NonFiniteError.Apply(metrics[2])
+	assert.EqualError(t, err, "metric prometheus-summary has non-finite values: quantile 0.5")
+	keep, err := NonFiniteError.Apply(metrics[3])
+	assert.NoError(t, err)
+	assert.True(t, keep)
+}
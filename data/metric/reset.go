@@ -0,0 +1,44 @@
+package metric
+
+import (
+	"github.com/newrelic/infra-integrations-sdk/v4/persist"
+)
+
+// ResetDetector tracks the values of cumulative counts between integration runs to detect decreases,
+// which usually mean the counter was reset or wrapped. Note the storer must be saved for values to be kept
+// between runs.
+type ResetDetector struct {
+	storer persist.Storer
+}
+
+// NewResetDetector creates a reset detector backed by the given storer.
+func NewResetDetector(storer persist.Storer) *ResetDetector {
+	return &ResetDetector{storer: storer}
+}
+
+// Check stores the value of a cumulative count metric and returns whether it's lower than the one stored in
+// the previous run, along with the previous value. The namespace (i.e. the entity name) distinguishes metrics
+// with the same name and dimensions from different sources. Other metric types are never reset.
+func (d *ResetDetector) Check(namespace string, m Metric) (previous float64, reset bool, err error) {
+	cc, ok := m.(*cumulativeCount)
+	if !ok {
+		return 0, false, nil
+	}
+
+	key := "reset|" + namespace + "|" + SeriesKey(m)
+	_, err = d.storer.Get(key, &previous)
+	d.storer.Set(key, cc.Value)
+
+	if err == persist.ErrNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return previous, cc.Value < previous, nil
+}
+
+// Save persists the stored values.
+func (d *ResetDetector) Save() error {
+	return d.storer.Save()
+}
@@ -0,0 +1,47 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/persist"
+)
+
+func Test_ResetDetector_Check(t *testing.T) {
+	d := NewResetDetector(persist.NewInMemoryStore())
+
+	check := func(namespace string, value float64) (float64, bool) {
+		cc, _ := NewCumulativeCount(now, "packets", value)
+		_ = cc.AddDimension("interface", "eth0")
+		previous, reset, err := d.Check(namespace, cc)
+		require.NoError(t, err)
+		return previous, reset
+	}
+
+	_, reset := check("router", 100)
+	assert.False(t, reset)
+	_, reset = check("router", 100)
+	assert.False(t, reset)
+	previous, reset := check("router", 20)
+	assert.True(t, reset)
+	assert.Equal(t, float64(100), previous)
+	_, reset = check("router", 30)
+	assert.False(t, reset)
+
+	// other entities have their own series
+	_, reset = check("switch", 10)
+	assert.False(t, reset)
+}
+
+func Test_ResetDetector_IgnoresOtherTypes(t *testing.T) {
+	d := NewResetDetector(persist.NewInMemoryStore())
+
+	for _, v := range []float64{10, 5} {
+		g, _ := NewGauge(now, "gauge", v)
+		_, reset, err := d.Check("entity", g)
+		assert.NoError(t, err)
+		assert.False(t, reset)
+	}
+}
@@ -32,30 +32,34 @@ const (
 	PROMETHEUS_HISTOGRAM SourceType = iota
 	// PROMETHEUS_SUMMARY is a summaru as defined by Prometheus
 	PROMETHEUS_SUMMARY SourceType = iota
+	// EXPONENTIAL_HISTOGRAM is a base-2 exponential histogram, also known as native histogram
+	EXPONENTIAL_HISTOGRAM SourceType = iota
 )
 
 // SourcesTypeToName metric sources list mapping its type to readable name.
 var SourcesTypeToName = map[SourceType]string{
-	GAUGE:                "gauge",
-	COUNT:                "count",
-	SUMMARY:              "summary",
-	CUMULATIVE_COUNT:     "cumulative-count",
-	RATE:                 "rate",
-	CUMULATIVE_RATE:      "cumulative-rate",
-	PROMETHEUS_HISTOGRAM: "prometheus-histogram",
-	PROMETHEUS_SUMMARY:   "prometheus-summary",
+	GAUGE:                 "gauge",
+	COUNT:                 "count",
+	SUMMARY:               "summary",
+	CUMULATIVE_COUNT:      "cumulative-count",
+	RATE:                  "rate",
+	CUMULATIVE_RATE:       "cumulative-rate",
+	PROMETHEUS_HISTOGRAM:  "prometheus-histogram",
+	PROMETHEUS_SUMMARY:    "prometheus-summary",
+	EXPONENTIAL_HISTOGRAM: "exponential-histogram",
 }
 
 // SourcesNameToType metric sources list mapping its name to type.
 var SourcesNameToType = map[string]SourceType{
-	"gauge":                GAUGE,
-	"count":                COUNT,
-	"summary":              SUMMARY,
-	"cumulative-count":     CUMULATIVE_COUNT,
-	"rate":                 RATE,
-	"cumulative-rate":      CUMULATIVE_RATE,
-	"prometheus-histogram": PROMETHEUS_HISTOGRAM,
-	"prometheus-summary":   PROMETHEUS_SUMMARY,
+	"gauge":                 GAUGE,
+	"count":                 COUNT,
+	"summary":               SUMMARY,
+	"cumulative-count":      CUMULATIVE_COUNT,
+	"rate":                  RATE,
+	"cumulative-rate":       CUMULATIVE_RATE,
+	"prometheus-histogram":  PROMETHEUS_HISTOGRAM,
+	"prometheus-summary":    PROMETHEUS_SUMMARY,
+	"exponential-histogram": EXPONENTIAL_HISTOGRAM,
 }
 
 // String fulfills stringer interface, returning empty string on invalid source types.
@@ -0,0 +1,48 @@
+// Package precision defines the precision of the timestamps written in the integration payload.
+// Timestamps are serialized as Unix seconds by default.
+package precision
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var current = int64(time.Second)
+
+// Set sets the precision used for the timestamps of the metrics, events and common blocks created
+// from now on. Accepted values are time.Second, time.Millisecond, time.Microsecond and time.Nanosecond.
+func Set(precision time.Duration) error {
+	switch precision {
+	case time.Second, time.Millisecond, time.Microsecond, time.Nanosecond:
+		atomic.StoreInt64(&current, int64(precision))
+		return nil
+	default:
+		return fmt.Errorf("unsupported timestamp precision: %s", precision)
+	}
+}
+
+// Get returns the current timestamp precision.
+func Get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&current))
+}
+
+// Timestamp converts a time into a Unix timestamp with the given precision, as returned by Get. Timestamps are
+// floored, so times before the Unix epoch are rounded down as well.
+func Timestamp(t time.Time, precision time.Duration) int64 {
+	if precision <= 0 {
+		precision = time.Second
+	}
+	perSecond := int64(time.Second / precision)
+	// the nanoseconds are always in the [0, 1s) range, so the division floors them
+	return t.Unix()*perSecond + int64(t.Nanosecond())/int64(precision)
+}
+
+// Time converts a Unix timestamp with the given precision back into a time.
+func Time(timestamp int64, precision time.Duration) time.Time {
+	if precision <= 0 {
+		precision = time.Second
+	}
+	perSecond := int64(time.Second / precision)
+	return time.Unix(timestamp/perSecond, timestamp%perSecond*int64(precision))
+}
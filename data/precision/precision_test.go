@@ -0,0 +1,56 @@
+package precision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	defer func() { _ = Set(time.Second) }()
+
+	assert.Equal(t, time.Second, Get())
+	assert.NoError(t, Set(time.Millisecond))
+	assert.Equal(t, time.Millisecond, Get())
+}
+
+func TestTimestamp(t *testing.T) {
+	ts := time.Unix(10000000, 123456789)
+
+	assert.Equal(t, int64(10000000), Timestamp(ts, time.Second))
+	assert.Equal(t, int64(10000000123), Timestamp(ts, time.Millisecond))
+	assert.Equal(t, int64(10000000123456), Timestamp(ts, time.Microsecond))
+	assert.Equal(t, int64(10000000123456789), Timestamp(ts, time.Nanosecond))
+	assert.Equal(t, time.Unix(10000000, 123000000), Time(Timestamp(ts, time.Millisecond), time.Millisecond))
+}
+
+func TestTimestampOutOfTheNanosecondsRange(t *testing.T) {
+	assert.Equal(t, int64(-62135596800), Timestamp(time.Time{}, time.Second))
+	assert.Equal(t, int64(-62135596800000), Timestamp(time.Time{}, time.Millisecond))
+	assert.True(t, time.Time{}.Equal(Time(-62135596800, time.Second)))
+
+	future := time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, int64(10413792000), Timestamp(future, time.Second))
+	assert.Equal(t, int64(10413792000000), Timestamp(future, time.Millisecond))
+	assert.True(t, future.Equal(Time(10413792000, time.Second)))
+}
+
+func TestTimestampBeforeTheEpochIsFloored(t *testing.T) {
+	ts := time.Unix(-1, 500000000)
+
+	assert.Equal(t, int64(-1), Timestamp(ts, time.Second))
+	assert.Equal(t, int64(-500), Timestamp(ts, time.Millisecond))
+	assert.True(t, ts.Equal(Time(-500, time.Millisecond)))
+	assert.True(t, time.Unix(-1, 0).Equal(Time(-1, time.Second)))
+}
+
+func TestSetUnsupportedPrecision(t *testing.T) {
+	assert.Error(t, Set(time.Minute))
+	assert.Error(t, Set(0))
+	assert.Equal(t, time.Second, Get())
+}
+
+func TestTimeDefaultsToSeconds(t *testing.T) {
+	assert.Equal(t, time.Unix(10000000, 0), Time(10000000, 0))
+}
@@ -5,4 +5,5 @@ go 1.13
 require (
 	github.com/newrelic/infrastructure-agent v0.0.0-20201127092132-00ac7efc0cc6
 	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.2.7
 )
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for the requests to a host whose circuit is open, see NewCircuitBreakerTransport.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// circuitBreakerTransport rejects the requests to failing hosts, see NewCircuitBreakerTransport.
+type circuitBreakerTransport struct {
+	base      http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+	lock      sync.Mutex
+	hosts     map[string]*circuit
+}
+
+// circuit holds the state of the circuit of a host.
+type circuit struct {
+	failures int
+	// the circuit is open until this time, and then lets a single trial request through
+	openUntil time.Time
+	trial     bool
+}
+
+// NewCircuitBreakerTransport returns a RoundTripper that opens the circuit of a host after the given number of
+// consecutive failed requests to it, either network errors or 5xx statuses, sent through the base RoundTripper
+// (or http.DefaultTransport if nil). Requests to a host with its circuit open fail with ErrCircuitOpen, without
+// being sent, until the cooldown passes. Then a single trial request is sent, closing the circuit if it succeeds
+// or opening it for another cooldown otherwise.
+func NewCircuitBreakerTransport(base http.RoundTripper, threshold int, cooldown time.Duration) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &circuitBreakerTransport{
+		base:      base,
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     map[string]*circuit{},
+	}
+}
+
+// RoundTrip fulfills http.RoundTripper interface.
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := t.allow(host); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	t.record(host, err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// allow returns ErrCircuitOpen if the request to the host must not be sent.
+func (t *circuitBreakerTransport) allow(host string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	c, ok := t.hosts[host]
+	if !ok || c.failures < t.threshold {
+		return nil
+	}
+	if c.trial || time.Now().Before(c.openUntil) {
+		return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+	}
+	c.trial = true
+	return nil
+}
+
+// record updates the circuit of the host with the result of a request.
+func (t *circuitBreakerTransport) record(host string, success bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	c, ok := t.hosts[host]
+	if !ok {
+		if success {
+			return
+		}
+		c = &circuit{}
+		t.hosts[host] = c
+	}
+	c.trial = false
+	if success {
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.failures >= t.threshold {
+		c.openUntil = time.Now().Add(t.cooldown)
+	}
+}
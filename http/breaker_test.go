@@ -0,0 +1,114 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// switchServer returns a server responding with the status stored in status, counting the requests.
+func switchServer(status, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+	}))
+}
+
+func getStatus(client *http.Client, url string) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestCircuitBreakerTransport(t *testing.T) {
+	status, requests := int32(http.StatusInternalServerError), int32(0)
+	srv := switchServer(&status, &requests)
+	defer srv.Close()
+	otherStatus, otherRequests := int32(http.StatusOK), int32(0)
+	other := switchServer(&otherStatus, &otherRequests)
+	defer other.Close()
+
+	cooldown := 50 * time.Millisecond
+	client := &http.Client{Transport: NewCircuitBreakerTransport(nil, 2, cooldown)}
+
+	// the circuit opens after two consecutive failures
+	for i := 0; i < 2; i++ {
+		code, err := getStatus(client, srv.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, code)
+	}
+	_, err := getStatus(client, srv.URL)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), requests, "requests aren't sent while the circuit is open")
+
+	// circuits are kept per host
+	code, err := getStatus(client, other.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	// a failed trial request opens the circuit again
+	time.Sleep(cooldown)
+	_, err = getStatus(client, srv.URL)
+	require.NoError(t, err)
+	_, err = getStatus(client, srv.URL)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(3), requests)
+
+	// a successful trial request closes the circuit
+	atomic.StoreInt32(&status, http.StatusOK)
+	time.Sleep(cooldown)
+	for i := 0; i < 3; i++ {
+		code, err = getStatus(client, srv.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, int32(6), requests)
+}
+
+func TestCircuitBreakerTransport_SuccessResetsFailures(t *testing.T) {
+	status, requests := int32(http.StatusServiceUnavailable), int32(0)
+	srv := switchServer(&status, &requests)
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewCircuitBreakerTransport(nil, 2, time.Minute)}
+	for _, s := range []int32{503, 200, 503, 200} {
+		atomic.StoreInt32(&status, s)
+		_, err := getStatus(client, srv.URL)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(4), requests)
+}
+
+func TestNewClient_RetriesAndCircuitBreaker(t *testing.T) {
+	status, requests := int32(http.StatusServiceUnavailable), int32(0)
+	srv := switchServer(&status, &requests)
+	defer srv.Close()
+
+	client, err := NewClient(FromArgs(args.HTTPClientArgumentList{
+		HTTPRetries:          5,
+		HTTPRetryBackoff:     time.Millisecond,
+		HTTPRetryMaxBackoff:  time.Millisecond,
+		HTTPBreakerThreshold: 3,
+		HTTPBreakerCooldown:  time.Minute,
+	}))
+	require.NoError(t, err)
+
+	_, err = getStatus(client, srv.URL)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(3), requests, "retries stop once the circuit opens")
+
+	_, err = NewClient(Retries(RetryConfig{MaxRetries: 1, InitialBackoff: time.Second, MaxBackoff: time.Millisecond}))
+	assert.Error(t, err)
+	_, err = NewClient(CircuitBreaker(0, time.Second))
+	assert.Error(t, err)
+}
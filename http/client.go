@@ -1,4 +1,5 @@
-// Package http provides an easy way to construct an http client with custom certificates and customizable timeout.
+// Package http provides an easy way to construct an http client with custom certificates and customizable timeout,
+// or further settings such as client certificates, proxy and authentication through the options of NewClient.
 // If you need to customize other attributes you can use the golang http package. https://golang.org/pkg/net/http/
 package http
 
@@ -16,75 +17,61 @@ import (
 
 // New creates a new http.Client with a custom certificate, which can be loaded from the passed CA Bundle file and/or
 // directory. If both CABundleFile and CABundleDir are empty arguments, it creates an unsecure HTTP client.
+// Use NewClient for further settings.
 func New(CABundleFile, CABundleDir string, httpTimeout time.Duration) (*http.Client, error) {
-	return _new(CABundleFile, CABundleDir, httpTimeout, "")
+	return NewClient(CABundle(CABundleFile, CABundleDir), Timeout(httpTimeout))
 }
 
 // NewAcceptInvalidHostname new http.Client with ability to accept HTTPS certificates that don't
-// match the hostname of the server they are connecting to.
+// match the hostname of the server they are connecting to. As for New, if both CABundleFile and CABundleDir are
+// empty it creates an unsecure HTTP client, ignoring the hostname.
 func NewAcceptInvalidHostname(CABundleFile, CABundleDir string, httpTimeout time.Duration, hostname string) (*http.Client, error) {
-	return _new(CABundleFile, CABundleDir, httpTimeout, hostname)
+	opts := []Option{CABundle(CABundleFile, CABundleDir), Timeout(httpTimeout)}
+	if CABundleFile != "" || CABundleDir != "" {
+		opts = append(opts, AcceptInvalidHostname(hostname))
+	}
+	return NewClient(opts...)
 }
 
-func _new(CABundleFile, CABundleDir string, httpTimeout time.Duration, acceptInvalidHostname string) (*http.Client, error) {
-	// go default http transport settings
-	t := &http.Transport{}
-
-	if CABundleFile != "" || CABundleDir != "" {
-		certs, err := getCertPool(CABundleFile, CABundleDir)
-		if err != nil {
-			return nil, err
+// verifyHostname replaces the default validation of the server certificates by the validation against the
+// given hostname.
+func verifyHostname(tlsConfig *tls.Config, hostname string) {
+	// Default validation is replaced with VerifyPeerCertificate.
+	// Note that when InsecureSkipVerify and VerifyPeerCertificate are in use,
+	// ConnectionState.VerifiedChains will be nil.
+	tlsConfig.InsecureSkipVerify = true
+	// While packages like net/http will implicitly set ServerName, the
+	// VerifyPeerCertificate callback can't access that value, so it has to be set
+	// explicitly here or in VerifyPeerCertificate on the client side. If in
+	// an http.Transport DialTLS callback, this can be obtained by passing
+	// the addr argument to net.SplitHostPort.
+	tlsConfig.ServerName = hostname
+	// Approximately equivalent to what crypto/tls does normally:
+	// https://github.com/golang/go/commit/29cfb4d3c3a97b6f426d1b899234da905be699aa
+	tlsConfig.VerifyPeerCertificate = func(certificates [][]byte, _ [][]*x509.Certificate) error {
+		certs := make([]*x509.Certificate, len(certificates))
+		for i, asn1Data := range certificates {
+			cert, err := x509.ParseCertificate(asn1Data)
+			if err != nil {
+				return errors.New("tls: failed to parse certificate from server: " + err.Error())
+			}
+			certs[i] = cert
 		}
 
-		t.TLSClientConfig = &tls.Config{
-			RootCAs: certs,
+		opts := x509.VerifyOptions{
+			Roots:         tlsConfig.RootCAs, // On the server side, use config.ClientCAs.
+			DNSName:       hostname,
+			Intermediates: x509.NewCertPool(),
+			// On the server side, set KeyUsages to ExtKeyUsageClientAuth. The
+			// default value is appropriate for clients side verification.
+			// KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		}
-
-		if acceptInvalidHostname != "" {
-			// Default validation is replaced with VerifyPeerCertificate.
-			// Note that when InsecureSkipVerify and VerifyPeerCertificate are in use,
-			// ConnectionState.VerifiedChains will be nil.
-			t.TLSClientConfig.InsecureSkipVerify = true
-			// While packages like net/http will implicitly set ServerName, the
-			// VerifyPeerCertificate callback can't access that value, so it has to be set
-			// explicitly here or in VerifyPeerCertificate on the client side. If in
-			// an http.Transport DialTLS callback, this can be obtained by passing
-			// the addr argument to net.SplitHostPort.
-			t.TLSClientConfig.ServerName = acceptInvalidHostname
-			// Approximately equivalent to what crypto/tls does normally:
-			// https://github.com/golang/go/commit/29cfb4d3c3a97b6f426d1b899234da905be699aa
-			t.TLSClientConfig.VerifyPeerCertificate = func(certificates [][]byte, _ [][]*x509.Certificate) error {
-				certs := make([]*x509.Certificate, len(certificates))
-				for i, asn1Data := range certificates {
-					cert, err := x509.ParseCertificate(asn1Data)
-					if err != nil {
-						return errors.New("tls: failed to parse certificate from server: " + err.Error())
-					}
-					certs[i] = cert
-				}
-
-				opts := x509.VerifyOptions{
-					Roots:         t.TLSClientConfig.RootCAs, // On the server side, use config.ClientCAs.
-					DNSName:       acceptInvalidHostname,
-					Intermediates: x509.NewCertPool(),
-					// On the server side, set KeyUsages to ExtKeyUsageClientAuth. The
-					// default value is appropriate for clients side verification.
-					// KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-				}
-				for _, cert := range certs[1:] {
-					opts.Intermediates.AddCert(cert)
-				}
-				_, err := certs[0].Verify(opts)
-				return err
-			}
-
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
 		}
+		_, err := certs[0].Verify(opts)
+		return err
 	}
-
-	return &http.Client{
-		Timeout:   httpTimeout,
-		Transport: t,
-	}, nil
 }
 
 func getCertPool(certFile string, certDirectory string) (*x509.CertPool, error) {
@@ -240,6 +240,17 @@ func Test_NewAcceptInvalidHostname(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_NewAcceptInvalidHostnameWithoutCABundleIgnoresTheHostname(t *testing.T) {
+	client, err := NewAcceptInvalidHostname("", "", time.Second, "foo.bar")
+	require.NoError(t, err)
+
+	tlsConfig := client.Transport.(*http.Transport).TLSClientConfig
+	if tlsConfig != nil {
+		assert.False(t, tlsConfig.InsecureSkipVerify)
+		assert.Nil(t, tlsConfig.VerifyPeerCertificate)
+	}
+}
+
 func certsetup(hostname string, ips []net.IP) (serverTLSConf *tls.Config, err error) {
 	// set up our CA certificate
 	ca := &x509.Certificate{
@@ -0,0 +1,507 @@
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/args"
+)
+
+// Option configures the client created by NewClient.
+type Option func(*config) error
+
+// config holds the settings of the client created by NewClient.
+type config struct {
+	timeout               time.Duration
+	caBundleFile          string
+	caBundleDir           string
+	acceptInvalidHostname string
+	certFile              string
+	keyFile               string
+	minTLSVersion         uint16
+	cipherSuites          []uint16
+	proxy                 *url.URL
+	noProxy               string
+	username              string
+	password              string
+	bearerToken           string
+	bearerTokenFile       string
+	headers               http.Header
+	authHosts             []string
+	retry                 RetryConfig
+	breakerThreshold      int
+	breakerCooldown       time.Duration
+}
+
+// tlsVersions are the accepted names of the TLS versions, see MinTLSVersion.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewClient creates a new http.Client configured by the given options. Without options, it creates an unsecure
+// HTTP client without timeout, as New does with empty arguments.
+//
+//	client, err := http.NewClient(
+//		http.CABundle("", "/etc/ssl/crt"),
+//		http.ClientCertificate("/etc/ssl/client.crt", "/etc/ssl/client.key"),
+//		http.BearerTokenFile("/var/run/secrets/token"),
+//		http.Timeout(10*time.Second),
+//	)
+func NewClient(opts ...Option) (*http.Client, error) {
+	c := config{headers: http.Header{}}
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+
+	authMethods := 0
+	for _, set := range []bool{c.username != "" || c.password != "", c.bearerToken != "", c.bearerTokenFile != ""} {
+		if set {
+			authMethods++
+		}
+	}
+	if authMethods > 1 {
+		return nil, errors.New("only one of basic authentication, bearer token or bearer token file can be set")
+	}
+
+	// go default http transport settings
+	t := &http.Transport{}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	t.TLSClientConfig = tlsConfig
+
+	if c.proxy != nil {
+		t.Proxy = proxyFunc(c.proxy, c.noProxy)
+	}
+
+	var rt http.RoundTripper = t
+	if len(c.headers) > 0 || authMethods > 0 {
+		rt = &requestTransport{base: t, config: c}
+		if c.bearerTokenFile != "" {
+			rt.(*requestTransport).tokenFile = &tokenFile{path: c.bearerTokenFile}
+		}
+	}
+
+	if c.breakerThreshold > 0 {
+		rt = NewCircuitBreakerTransport(rt, c.breakerThreshold, c.breakerCooldown)
+	}
+	if c.retry.MaxRetries > 0 {
+		rt = NewRetryTransport(rt, c.retry)
+	}
+
+	return &http.Client{
+		Timeout:   c.timeout,
+		Transport: rt,
+	}, nil
+}
+
+// FromArgs configures the client from the HTTP client arguments.
+func FromArgs(a args.HTTPClientArgumentList) Option {
+	return func(c *config) error {
+		opts := []Option{
+			Timeout(time.Duration(a.HTTPTimeout) * time.Second),
+			CABundle(a.HTTPCaBundleFile, a.HTTPCaBundleDir),
+			Headers(a.HTTPHeaders),
+			CipherSuites(a.HTTPCipherSuites...),
+		}
+		if a.HTTPClientCertFile != "" || a.HTTPClientKeyFile != "" {
+			opts = append(opts, ClientCertificate(a.HTTPClientCertFile, a.HTTPClientKeyFile))
+		}
+		if a.HTTPMinTLSVersion != "" {
+			opts = append(opts, MinTLSVersion(a.HTTPMinTLSVersion))
+		}
+		if a.HTTPProxy != "" {
+			opts = append(opts, Proxy(a.HTTPProxy, a.HTTPNoProxy))
+		}
+		if a.HTTPUsername != "" || a.HTTPPassword != "" {
+			opts = append(opts, BasicAuth(a.HTTPUsername, a.HTTPPassword))
+		}
+		if a.HTTPBearerToken != "" {
+			opts = append(opts, BearerToken(a.HTTPBearerToken))
+		}
+		if a.HTTPBearerTokenFile != "" {
+			opts = append(opts, BearerTokenFile(a.HTTPBearerTokenFile))
+		}
+		if a.HTTPRetries > 0 {
+			opts = append(opts, Retries(RetryConfig{
+				MaxRetries:     a.HTTPRetries,
+				InitialBackoff: a.HTTPRetryBackoff,
+				MaxBackoff:     a.HTTPRetryMaxBackoff,
+			}))
+		}
+		if a.HTTPBreakerThreshold > 0 {
+			opts = append(opts, CircuitBreaker(a.HTTPBreakerThreshold, a.HTTPBreakerCooldown))
+		}
+
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Timeout sets the time limit of the requests, including connection, redirects and reading the response body.
+func Timeout(timeout time.Duration) Option {
+	return func(c *config) error {
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// CABundle verifies the server certificates with the certificates loaded from the CA bundle file and/or the .pem
+// files of the CA bundle directory, see New.
+func CABundle(file, dir string) Option {
+	return func(c *config) error {
+		c.caBundleFile = file
+		c.caBundleDir = dir
+		return nil
+	}
+}
+
+// AcceptInvalidHostname accepts HTTPS certificates that don't match the hostname of the server they are connecting
+// to, verifying them against the given hostname instead, see NewAcceptInvalidHostname.
+func AcceptInvalidHostname(hostname string) Option {
+	return func(c *config) error {
+		c.acceptInvalidHostname = hostname
+		return nil
+	}
+}
+
+// ClientCertificate authenticates the client to the server with the certificate and key in the given PEM
+// files (mutual TLS).
+func ClientCertificate(certFile, keyFile string) Option {
+	return func(c *config) error {
+		if certFile == "" || keyFile == "" {
+			return errors.New("both client certificate and key files must be set")
+		}
+		c.certFile = certFile
+		c.keyFile = keyFile
+		return nil
+	}
+}
+
+// MinTLSVersion sets the minimum TLS version accepted: 1.0, 1.1, 1.2 or 1.3.
+func MinTLSVersion(version string) Option {
+	return func(c *config) error {
+		v, ok := tlsVersions[version]
+		if !ok {
+			return fmt.Errorf("unknown TLS version %q", version)
+		}
+		c.minTLSVersion = v
+		return nil
+	}
+}
+
+// CipherSuites restricts the cipher suites of TLS connections up to 1.2 to the given ones, named as in the
+// crypto/tls package, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. TLS 1.3 cipher suites aren't configurable.
+func CipherSuites(names ...string) Option {
+	return func(c *config) error {
+		known := map[string]uint16{}
+		for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			known[s.Name] = s.ID
+		}
+
+		for _, name := range names {
+			id, ok := known[name]
+			if !ok {
+				return fmt.Errorf("unknown cipher suite %q", name)
+			}
+			c.cipherSuites = append(c.cipherSuites, id)
+		}
+		return nil
+	}
+}
+
+// Proxy sends the requests through the proxy with the given URL, except the requests to the hosts matching the
+// comma-separated noProxy list, in the format of the NO_PROXY environment variable: host names (also matching
+// their subdomains, with or without a leading dot), IP addresses or CIDR ranges, optionally with a port, or * to
+// match every host.
+func Proxy(proxyURL, noProxy string) Option {
+	return func(c *config) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %s", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q: scheme and host are required", proxyURL)
+		}
+		c.proxy = u
+		c.noProxy = noProxy
+		return nil
+	}
+}
+
+// BasicAuth authenticates the requests with the given username and password.
+func BasicAuth(username, password string) Option {
+	return func(c *config) error {
+		c.username = username
+		c.password = password
+		return nil
+	}
+}
+
+// BearerToken authenticates the requests with the given bearer token.
+func BearerToken(token string) Option {
+	return func(c *config) error {
+		c.bearerToken = token
+		return nil
+	}
+}
+
+// BearerTokenFile authenticates the requests with the bearer token read from the given file, which is read
+// again when it's modified, so rotated tokens are refreshed.
+func BearerTokenFile(path string) Option {
+	return func(c *config) error {
+		if _, err := readToken(path); err != nil {
+			return err
+		}
+		c.bearerTokenFile = path
+		return nil
+	}
+}
+
+// AuthHosts restricts the authentication to the requests sent to the given hosts, which are matched as the
+// noProxy hosts (see Proxy). By default, credentials are sent to the host of every request made by the client,
+// but not to the hosts the requests are redirected to.
+func AuthHosts(hosts ...string) Option {
+	return func(c *config) error {
+		c.authHosts = hosts
+		return nil
+	}
+}
+
+// Headers sets headers on every request, unless the request sets them already.
+func Headers(headers map[string]string) Option {
+	return func(c *config) error {
+		for name, value := range headers {
+			c.headers.Set(name, value)
+		}
+		return nil
+	}
+}
+
+// Retries retries the failed idempotent requests, see NewRetryTransport. Each attempt goes through the circuit
+// breaker, if any.
+func Retries(retry RetryConfig) Option {
+	return func(c *config) error {
+		if retry.MaxRetries < 0 || retry.InitialBackoff < 0 || retry.MaxBackoff < retry.InitialBackoff {
+			return errors.New("retries and backoffs can't be negative, nor the maximum backoff lower than the initial")
+		}
+		c.retry = retry
+		return nil
+	}
+}
+
+// CircuitBreaker stops sending requests to a host for the cooldown after the given number of consecutive failed
+// requests to it, see NewCircuitBreakerTransport.
+func CircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *config) error {
+		if threshold < 1 || cooldown <= 0 {
+			return errors.New("circuit breaker threshold and cooldown must be positive")
+		}
+		c.breakerThreshold = threshold
+		c.breakerCooldown = cooldown
+		return nil
+	}
+}
+
+// tlsConfig returns the TLS configuration of the client, or nil if there isn't any TLS setting.
+func (c *config) tlsConfig() (*tls.Config, error) {
+	hasCA := c.caBundleFile != "" || c.caBundleDir != ""
+	if !hasCA && c.certFile == "" && c.minTLSVersion == 0 && len(c.cipherSuites) == 0 && c.acceptInvalidHostname == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   c.minTLSVersion,
+		CipherSuites: c.cipherSuites,
+	}
+
+	if hasCA {
+		certs, err := getCertPool(c.caBundleFile, c.caBundleDir)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = certs
+	}
+
+	if c.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.acceptInvalidHostname != "" {
+		verifyHostname(tlsConfig, c.acceptInvalidHostname)
+	}
+
+	return tlsConfig, nil
+}
+
+// proxyFunc returns the proxy of the requests, skipping the hosts matching the noProxy list (see Proxy).
+func proxyFunc(proxy *url.URL, noProxy string) func(*http.Request) (*url.URL, error) {
+	var patterns []string
+	for _, p := range strings.Split(noProxy, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		for _, p := range patterns {
+			if matchNoProxy(p, req.URL) {
+				return nil, nil
+			}
+		}
+		return proxy, nil
+	}
+}
+
+func matchNoProxy(pattern string, u *url.URL) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+
+	if _, cidr, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && cidr.Contains(ip)
+	}
+
+	if h, p, err := net.SplitHostPort(pattern); err == nil {
+		if p != port {
+			return false
+		}
+		pattern = h
+	}
+	pattern = strings.TrimPrefix(pattern, "*")
+	pattern = strings.TrimPrefix(pattern, ".")
+
+	if ip := net.ParseIP(pattern); ip != nil {
+		return ip.Equal(net.ParseIP(host))
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// requestTransport sets the default headers and the authentication of the requests.
+type requestTransport struct {
+	base      http.RoundTripper
+	config    config
+	tokenFile *tokenFile
+}
+
+// RoundTrip fulfills http.RoundTripper interface.
+func (t *requestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests must not be modified by round trippers
+	req = req.Clone(req.Context())
+
+	for name, values := range t.config.headers {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = append([]string(nil), values...)
+		}
+	}
+
+	// the credentials set by the request take precedence
+	if req.Header.Get("Authorization") != "" || !t.authorized(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	switch {
+	case t.config.username != "" || t.config.password != "":
+		req.SetBasicAuth(t.config.username, t.config.password)
+	case t.config.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+t.config.bearerToken)
+	case t.tokenFile != nil:
+		token, err := t.tokenFile.token()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// authorized returns true if the credentials can be sent along the request: its host is one of the AuthHosts or,
+// if they are not set, it's not a redirection to a host other than the one of the original request.
+func (t *requestTransport) authorized(req *http.Request) bool {
+	if len(t.config.authHosts) > 0 {
+		for _, h := range t.config.authHosts {
+			if matchNoProxy(h, req.URL) {
+				return true
+			}
+		}
+		return false
+	}
+
+	original := req
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+	}
+	return original.URL.Host == req.URL.Host
+}
+
+// tokenFile reads a bearer token from a file, reading it again when the file is modified.
+type tokenFile struct {
+	path    string
+	lock    sync.Mutex
+	modTime time.Time
+	value   string
+}
+
+func (f *tokenFile) token() (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("can't read bearer token: %s", err)
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.value == "" || !info.ModTime().Equal(f.modTime) {
+		value, err := readToken(f.path)
+		if err != nil {
+			return "", err
+		}
+		f.value = value
+		f.modTime = info.ModTime()
+	}
+	return f.value, nil
+}
+
+func readToken(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("can't read bearer token: %s", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file %s is empty", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,301 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCert writes a self-signed client certificate and its key into the directory.
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2021),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile, cert
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	return dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// recordingServer returns a server keeping the headers of the last request.
+func recordingServer(headers *http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = r.Header
+	}))
+}
+
+func get(t *testing.T, client *http.Client, url string) {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	_, _ = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+}
+
+func TestNewClient_ClientCertificate(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	certFile, keyFile, cert := writeClientCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	require.NoError(t, writeCApem(t, nil, srv, dir, "ca.pem"))
+
+	client, err := NewClient(CABundle("ca.pem", dir), ClientCertificate(certFile, keyFile), Timeout(time.Second))
+	require.NoError(t, err)
+	get(t, client, srv.URL)
+
+	client, err = NewClient(CABundle("ca.pem", dir), Timeout(time.Second))
+	require.NoError(t, err)
+	_, err = client.Get(srv.URL)
+	assert.Error(t, err, "server requires a client certificate")
+
+	_, err = NewClient(ClientCertificate(certFile, ""))
+	assert.Error(t, err)
+	_, err = NewClient(ClientCertificate(certFile, certFile))
+	assert.Error(t, err)
+}
+
+func TestNewClient_TLSSettings(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+	require.NoError(t, writeCApem(t, nil, srv, dir, "ca.pem"))
+
+	client, err := NewClient(CABundle("ca.pem", dir), MinTLSVersion("1.2"),
+		CipherSuites("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"))
+	require.NoError(t, err)
+	get(t, client, srv.URL)
+
+	client, err = NewClient(CABundle("ca.pem", dir), MinTLSVersion("1.3"))
+	require.NoError(t, err)
+	_, err = client.Get(srv.URL)
+	assert.Error(t, err, "server doesn't support TLS 1.3")
+
+	_, err = NewClient(MinTLSVersion("1.4"))
+	assert.EqualError(t, err, `unknown TLS version "1.4"`)
+	_, err = NewClient(CipherSuites("TLS_UNKNOWN"))
+	assert.EqualError(t, err, `unknown cipher suite "TLS_UNKNOWN"`)
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	var headers http.Header
+	srv := recordingServer(&headers)
+	defer srv.Close()
+
+	client, err := NewClient(Proxy(proxy.URL, "localhost, 127.0.0.1"))
+	require.NoError(t, err)
+	get(t, client, "http://metrics.example.com/status")
+	get(t, client, srv.URL)
+	assert.Equal(t, []string{"http://metrics.example.com/status"}, proxied)
+	assert.NotNil(t, headers, "request to excluded host is not proxied")
+
+	_, err = NewClient(Proxy("proxy:3128", ""))
+	assert.Error(t, err)
+}
+
+func Test_matchNoProxy(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		url     string
+		match   bool
+	}{
+		{"*", "http://example.com", true},
+		{"example.com", "http://example.com", true},
+		{"example.com", "http://api.example.com", true},
+		{".example.com", "http://api.example.com", true},
+		{"*.example.com", "http://api.example.com", true},
+		{"example.com", "http://notexample.com", false},
+		{"example.com:8080", "http://example.com:8080", true},
+		{"example.com:8080", "http://example.com", false},
+		{"example.com:443", "https://example.com", true},
+		{"10.0.0.0/8", "http://10.1.2.3:9090", true},
+		{"10.0.0.0/8", "http://192.168.1.1", false},
+		{"10.0.0.0/8", "http://example.com", false},
+		{"::1", "http://[::1]:8080", true},
+		{"127.0.0.1", "http://127.0.0.2", false},
+	} {
+		u, err := url.Parse(tc.url)
+		require.NoError(t, err)
+		assert.Equal(t, tc.match, matchNoProxy(tc.pattern, u), "%s %s", tc.pattern, tc.url)
+	}
+}
+
+func TestNewClient_Authentication(t *testing.T) {
+	var headers http.Header
+	srv := recordingServer(&headers)
+	defer srv.Close()
+
+	client, err := NewClient(BasicAuth("user", "pass"))
+	require.NoError(t, err)
+	get(t, client, srv.URL)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", headers.Get("Authorization"))
+
+	client, err = NewClient(BearerToken("t0k3n"))
+	require.NoError(t, err)
+	get(t, client, srv.URL)
+	assert.Equal(t, "Bearer t0k3n", headers.Get("Authorization"))
+
+	_, err = NewClient(BasicAuth("user", "pass"), BearerToken("t0k3n"))
+	assert.Error(t, err)
+}
+
+func TestNewClient_AuthenticationIsNotSentToOtherHosts(t *testing.T) {
+	var headers http.Header
+	other := recordingServer(&headers)
+	defer other.Close()
+	redirecting := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusFound))
+	defer redirecting.Close()
+
+	client, err := NewClient(BearerToken("t0k3n"))
+	require.NoError(t, err)
+	get(t, client, redirecting.URL)
+	assert.Empty(t, headers.Get("Authorization"), "credentials are not sent to the redirected host")
+
+	client, err = NewClient(BearerToken("t0k3n"), AuthHosts(redirecting.Listener.Addr().String()))
+	require.NoError(t, err)
+	get(t, client, other.URL)
+	assert.Empty(t, headers.Get("Authorization"), "credentials are only sent to the auth hosts")
+
+	client, err = NewClient(BearerToken("t0k3n"), AuthHosts(other.Listener.Addr().String()))
+	require.NoError(t, err)
+	get(t, client, redirecting.URL)
+	assert.Equal(t, "Bearer t0k3n", headers.Get("Authorization"))
+}
+
+func TestNewClient_RequestAuthenticationIsKept(t *testing.T) {
+	var headers http.Header
+	srv := recordingServer(&headers)
+	defer srv.Close()
+
+	client, err := NewClient(BasicAuth("user", "pass"))
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer request-t0k3n")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "Bearer request-t0k3n", headers.Get("Authorization"))
+}
+
+func TestNewClient_BearerTokenFileIsRefreshed(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	tokenPath := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenPath, []byte("first\n"), 0600))
+
+	var headers http.Header
+	srv := recordingServer(&headers)
+	defer srv.Close()
+
+	client, err := NewClient(BearerTokenFile(tokenPath))
+	require.NoError(t, err)
+	get(t, client, srv.URL)
+	assert.Equal(t, "Bearer first", headers.Get("Authorization"))
+
+	require.NoError(t, ioutil.WriteFile(tokenPath, []byte("second\n"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(tokenPath, later, later))
+	get(t, client, srv.URL)
+	assert.Equal(t, "Bearer second", headers.Get("Authorization"))
+
+	_, err = NewClient(BearerTokenFile(filepath.Join(dir, "missing")))
+	assert.Error(t, err)
+}
+
+func TestNewClient_Headers(t *testing.T) {
+	var headers http.Header
+	srv := recordingServer(&headers)
+	defer srv.Close()
+
+	client, err := NewClient(Headers(map[string]string{"X-Api-Key": "key", "User-Agent": "nri-test"}))
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Api-Key", "request-key")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "request-key", headers.Get("X-Api-Key"), "request headers take precedence")
+	assert.Equal(t, "nri-test", headers.Get("User-Agent"))
+	assert.Empty(t, req.Header.Get("User-Agent"), "request is not modified")
+}
+
+func TestNewClient_FromArgs(t *testing.T) {
+	var headers http.Header
+	srv := recordingServer(&headers)
+	defer srv.Close()
+
+	client, err := NewClient(FromArgs(args.HTTPClientArgumentList{
+		HTTPTimeout:     5,
+		HTTPBearerToken: "t0k3n",
+		HTTPHeaders:     map[string]string{"X-Api-Key": "key"},
+		HTTPProxy:       "http://proxy:3128",
+		HTTPNoProxy:     "127.0.0.1",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+	get(t, client, srv.URL)
+	assert.Equal(t, "Bearer t0k3n", headers.Get("Authorization"))
+	assert.Equal(t, "key", headers.Get("X-Api-Key"))
+
+	_, err = NewClient(FromArgs(args.HTTPClientArgumentList{HTTPMinTLSVersion: "1.9"}))
+	assert.Error(t, err)
+}
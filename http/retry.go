@@ -0,0 +1,144 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryConfig configures the retries of the transport created by NewRetryTransport.
+type RetryConfig struct {
+	// MaxRetries is the number of times a failed request is retried. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry, doubled on every further retry up to MaxBackoff. A
+	// random jitter of up to half the wait is subtracted, so clients don't retry in lockstep.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// retryTransport retries the failed idempotent requests, see NewRetryTransport.
+type retryTransport struct {
+	base   http.RoundTripper
+	config RetryConfig
+}
+
+// NewRetryTransport returns a RoundTripper retrying the idempotent requests sent through the base one (or
+// http.DefaultTransport if nil) when they fail with a network error or a 429, 502, 503 or 504 status, waiting an
+// exponential backoff with jitter between attempts.
+//
+// A Retry-After header in the response replaces the backoff, unless it's longer than MaxBackoff, in which case the
+// response is returned. Requests are idempotent if their method is GET, HEAD, OPTIONS, TRACE, PUT or DELETE, or
+// they have the Idempotency-Key header, and their body can be sent again (see http.Request.GetBody).
+//
+// Note the http.Client timeout covers all the attempts of a request.
+func NewRetryTransport(base http.RoundTripper, config RetryConfig) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, config: config}
+}
+
+// RoundTrip fulfills http.RoundTripper interface.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isRetryable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if attempt >= t.config.MaxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if retryAfter > t.config.MaxBackoff {
+					return resp, nil
+				}
+				wait = retryAfter
+			}
+			// drain the body so the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the jittered wait before the retry following the given attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	wait := t.config.InitialBackoff
+	for i := 0; i < attempt && wait < t.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > t.config.MaxBackoff {
+		wait = t.config.MaxBackoff
+	}
+	if half := int64(wait / 2); half > 0 {
+		wait -= time.Duration(rand.Int63n(half))
+	}
+	return wait
+}
+
+func isRetryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header["Idempotency-Key"]
+	return ok
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && !errors.Is(err, ErrCircuitOpen)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
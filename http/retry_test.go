@@ -0,0 +1,193 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetries = RetryConfig{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+// roundTripperFunc adapts a function to the http.RoundTripper interface.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// statusServer returns a server responding with the given statuses in order, and then with 200, counting the
+// requests.
+func statusServer(requests *int32, headers http.Header, statuses ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(requests, 1))
+		if n > len(statuses) {
+			body, _ := ioutil.ReadAll(r.Body)
+			_, _ = w.Write(body)
+			return
+		}
+		for name, values := range headers {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(statuses[n-1])
+	}))
+}
+
+func TestRetryTransport_RetriesTransientFailures(t *testing.T) {
+	var requests int32
+	srv := statusServer(&requests, nil, http.StatusServiceUnavailable, http.StatusBadGateway)
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetries)}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), requests)
+}
+
+func TestRetryTransport_GivesUpAfterMaxRetries(t *testing.T) {
+	var requests int32
+	srv := statusServer(&requests, nil, 503, 503, 503, 503, 503)
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetries)}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(4), requests)
+}
+
+func TestRetryTransport_DoesNotRetryOtherStatuses(t *testing.T) {
+	var requests int32
+	srv := statusServer(&requests, nil, http.StatusInternalServerError, http.StatusNotFound)
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetries)}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(1), requests)
+}
+
+func TestRetryTransport_OnlyRetriesIdempotentRequests(t *testing.T) {
+	var requests int32
+	srv := statusServer(&requests, nil, 503, 503)
+	defer srv.Close()
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetries)}
+
+	resp, err := client.Post(srv.URL, "text/plain", bytes.NewBufferString("payload"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), requests)
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString("payload"))
+	require.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "1")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "payload", string(body), "body is sent again")
+	assert.Equal(t, int32(3), requests)
+}
+
+func TestRetryTransport_RetriesNetworkErrors(t *testing.T) {
+	attempts := 0
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	resp, err := NewRetryTransport(base, testRetries).RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryTransport_RetryAfter(t *testing.T) {
+	var requests int32
+	srv := statusServer(&requests, http.Header{"Retry-After": {"0"}}, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetries)}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), requests)
+
+	requests = 0
+	srv = statusServer(&requests, http.Header{"Retry-After": {"120"}}, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	resp, err = client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "Retry-After over the maximum backoff")
+	assert.Equal(t, int32(1), requests)
+}
+
+func TestRetryTransport_StopsWaitingOnCancellation(t *testing.T) {
+	var requests int32
+	srv := statusServer(&requests, nil, 503, 503)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: NewRetryTransport(nil, RetryConfig{MaxRetries: 3, InitialBackoff: time.Minute, MaxBackoff: time.Minute})}
+	_, err = client.Do(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), requests)
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	rt := &retryTransport{config: RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 10; i++ {
+			wait := rt.backoff(attempt)
+			assert.True(t, wait > max/2 && wait <= max, "attempt %d waits %s", attempt, wait)
+		}
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	wait, ok := parseRetryAfter("3")
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, wait)
+
+	wait, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.True(t, wait > 59*time.Minute && wait <= time.Hour)
+
+	wait, ok = parseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), wait)
+
+	for _, invalid := range []string{"", "-1", "soon"} {
+		_, ok = parseRetryAfter(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
@@ -0,0 +1,148 @@
+package integration
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+)
+
+// Aggregator collects samples of noisy values during an integration run and, when flushed, emits a summary
+// metric (count, average, sum, min and max) per series into its entity. Samples are grouped in series by
+// metric name and dimensions.
+// It is safe for concurrent use.
+type Aggregator struct {
+	lock      sync.Mutex
+	entity    *Entity
+	quantiles []float64
+	series    map[string]*aggregatedSeries
+}
+
+type aggregatedSeries struct {
+	name       string
+	dimensions map[string]interface{}
+	summary    *metric.SummaryBuilder
+	// quantiles is only set when quantiles are requested
+	quantiles *metric.PrometheusSummaryBuilder
+}
+
+// NewAggregator creates an aggregator emitting into the entity. For every given quantile, which must be in the
+// [0, 1] range, a gauge named after the metric and the percentile (e.g. "queue.depth.p99" for 0.99) is emitted
+// on flush along with the summary. Quantiles are estimated with the P² algorithm, so samples aren't kept in memory.
+func (e *Entity) NewAggregator(quantiles ...float64) (*Aggregator, error) {
+	if _, err := metric.NewPrometheusSummaryBuilder(quantiles...); err != nil {
+		return nil, err
+	}
+
+	return &Aggregator{
+		entity:    e,
+		quantiles: quantiles,
+		series:    map[string]*aggregatedSeries{},
+	}, nil
+}
+
+// Sample adds a sample of the series with the given name and dimensions, whose values can be of any type
+// supported by metric.AttributeValue. NaN samples are ignored.
+func (a *Aggregator) Sample(name string, value float64, dimensions map[string]interface{}) error {
+	if len(name) == 0 {
+		return errors.New("name cannot be empty")
+	}
+
+	key, err := metric.DimensionsSeriesKey(name, dimensions)
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	s, ok := a.series[key]
+	if !ok {
+		s = &aggregatedSeries{
+			name:       name,
+			dimensions: make(map[string]interface{}, len(dimensions)),
+			summary:    metric.NewSummaryBuilder(),
+		}
+		for k, v := range dimensions {
+			s.dimensions[k] = v
+		}
+		if len(a.quantiles) > 0 {
+			// quantiles were already validated when creating the aggregator
+			s.quantiles, _ = metric.NewPrometheusSummaryBuilder(a.quantiles...)
+		}
+		a.series[key] = s
+	}
+
+	s.summary.Observe(value)
+	if s.quantiles != nil {
+		s.quantiles.Observe(value)
+	}
+	return nil
+}
+
+// Flush emits the aggregated metrics of every series into the entity and resets the aggregator.
+func (a *Aggregator) Flush(timestamp time.Time) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	keys := make([]string, 0, len(a.series))
+	for k := range a.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := a.series[k]
+
+		summary, err := s.summary.Build(timestamp, s.name)
+		if err != nil {
+			return err
+		}
+		if err = addDimensions(summary, s.dimensions); err != nil {
+			return err
+		}
+		a.entity.AddMetric(summary)
+
+		if s.quantiles == nil {
+			continue
+		}
+		ps, err := s.quantiles.Build(timestamp, s.name)
+		if err != nil {
+			return err
+		}
+		for _, q := range ps.GetValue().Quantiles {
+			if q.Value == nil {
+				continue
+			}
+			g, err := metric.NewGauge(timestamp, s.name+".p"+percentileLabel(*q.Quantile), *q.Value)
+			if err != nil {
+				return err
+			}
+			if err = addDimensions(g, s.dimensions); err != nil {
+				return err
+			}
+			a.entity.AddMetric(g)
+		}
+	}
+
+	a.series = map[string]*aggregatedSeries{}
+	return nil
+}
+
+func addDimensions(m metric.Metric, dimensions map[string]interface{}) error {
+	for k, v := range dimensions {
+		if err := m.AddTypedDimension(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// percentileLabel formats a quantile as a percentile, e.g. 0.999 as "99.9"
+func percentileLabel(q float64) string {
+	return strconv.FormatFloat(math.Round(q*100000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+)
+
+func TestAggregator_FlushEmitsSummaries(t *testing.T) {
+	e, err := newEntity("queue", "test", "")
+	require.NoError(t, err)
+	a, err := e.NewAggregator()
+	require.NoError(t, err)
+
+	for _, v := range []float64{3, 1, 2, math.NaN()} {
+		assert.NoError(t, a.Sample("queue.depth", v, map[string]interface{}{"queue": "orders"}))
+	}
+	assert.NoError(t, a.Sample("queue.depth", 10, map[string]interface{}{"queue": "payments"}))
+
+	require.NoError(t, a.Flush(time.Unix(10000000, 0)))
+	require.Len(t, e.Metrics, 2)
+
+	orders := e.Metrics[0].(metric.SummaryMetric)
+	assert.Equal(t, "queue.depth", orders.GetName())
+	assert.Equal(t, "orders", orders.Dimension("queue"))
+	assert.Equal(t, float64(3), *orders.GetValue().Count)
+	assert.Equal(t, float64(2), *orders.GetValue().Average)
+	assert.Equal(t, float64(6), *orders.GetValue().Sum)
+	assert.Equal(t, float64(1), *orders.GetValue().Min)
+	assert.Equal(t, float64(3), *orders.GetValue().Max)
+
+	payments := e.Metrics[1].(metric.SummaryMetric)
+	assert.Equal(t, "payments", payments.Dimension("queue"))
+	assert.Equal(t, float64(1), *payments.GetValue().Count)
+
+	// flushing resets the aggregator
+	require.NoError(t, a.Flush(time.Unix(10000000, 0)))
+	assert.Len(t, e.Metrics, 2)
+}
+
+func TestAggregator_FlushEmitsPercentiles(t *testing.T) {
+	e, err := newEntity("queue", "test", "")
+	require.NoError(t, err)
+	a, err := e.NewAggregator(0.5, 0.999)
+	require.NoError(t, err)
+
+	for v := 1; v <= 101; v++ {
+		assert.NoError(t, a.Sample("queue.depth", float64(v), map[string]interface{}{"shard": 1}))
+	}
+
+	require.NoError(t, a.Flush(time.Unix(10000000, 0)))
+	require.Len(t, e.Metrics, 3)
+
+	p50 := e.Metrics[1].(metric.NumericMetric)
+	assert.Equal(t, "queue.depth.p50", p50.GetName())
+	// quantiles are estimated, not exact
+	assert.InDelta(t, 51, p50.GetValue(), 1)
+	assert.Equal(t, int64(1), p50.TypedDimension("shard"))
+
+	p999 := e.Metrics[2].(metric.NumericMetric)
+	assert.Equal(t, "queue.depth.p99.9", p999.GetName())
+	assert.InDelta(t, 100.9, p999.GetValue(), 3)
+}
+
+func TestAggregator_Errors(t *testing.T) {
+	e, err := newEntity("queue", "test", "")
+	require.NoError(t, err)
+
+	_, err = e.NewAggregator(1.5)
+	assert.Error(t, err)
+
+	a, err := e.NewAggregator()
+	require.NoError(t, err)
+	assert.Error(t, a.Sample("", 1, nil))
+	assert.Error(t, a.Sample("queue.depth", 1, map[string]interface{}{"invalid": []int{}}))
+}
@@ -2,6 +2,7 @@ package integration
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/newrelic/infra-integrations-sdk/v4/data/inventory"
 	"github.com/newrelic/infra-integrations-sdk/v4/data/metadata"
 	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 )
 
 // Entity is the producer of the data. Entity could be a host, a container, a pod, or whatever unit of meaning.
@@ -76,12 +78,30 @@ func (e *Entity) AddCommonDimension(key string, value string) {
 	e.CommonDimensions.Attributes[key] = value
 }
 
-// AddCommonTimestamp adds a new common timestamp to the entity.
+// AddTypedCommonDimension adds a new dimension with a numeric, boolean or string value to every metric
+// within the entity. Values are validated the same way as metric dimensions, see metric.AttributeValue.
+func (e *Entity) AddTypedCommonDimension(key string, value interface{}) error {
+	if len(key) == 0 {
+		return errors.New("key cannot be empty")
+	}
+	v, err := metric.AttributeValue(value)
+	if err != nil {
+		return fmt.Errorf("invalid common dimension %s: %s", key, err)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.CommonDimensions.Attributes[key] = v
+	return nil
+}
+
+// AddCommonTimestamp adds a new common timestamp to the entity, using the configured timestamp precision.
 func (e *Entity) AddCommonTimestamp(timestamp time.Time) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
 
-	t := timestamp.Unix()
+	t := precision.Timestamp(timestamp, precision.Get())
 	e.CommonDimensions.Timestamp = &t
 }
 
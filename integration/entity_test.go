@@ -263,6 +263,24 @@ func TestEntity_AddCommonDimension(t *testing.T) {
 	}
 }
 
+func TestEntity_AddTypedCommonDimension(t *testing.T) {
+	e := newHostEntity()
+
+	assert.NoError(t, e.AddTypedCommonDimension("partition", 3))
+	assert.NoError(t, e.AddTypedCommonDimension("ratio", float32(0.5)))
+	assert.NoError(t, e.AddTypedCommonDimension("leader", true))
+	assert.NoError(t, e.AddTypedCommonDimension("topic", "orders"))
+	assert.Error(t, e.AddTypedCommonDimension("", 1))
+	assert.Error(t, e.AddTypedCommonDimension("invalid", []string{"a"}))
+
+	assert.Equal(t, map[string]interface{}{
+		"partition": int64(3),
+		"ratio":     float64(0.5),
+		"leader":    true,
+		"topic":     "orders",
+	}, e.CommonDimensions.Attributes)
+}
+
 func TestEntity_AddCommonTimestamp(t *testing.T) {
 	asPtr := func(i int64) *int64 {
 		return &i
@@ -12,7 +12,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/newrelic/infra-integrations-sdk/v4/data/event"
 	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 
 	"github.com/newrelic/infra-integrations-sdk/v4/args"
 	"github.com/newrelic/infra-integrations-sdk/v4/log"
@@ -21,8 +23,13 @@ import (
 // Custom attribute keys:
 const (
 	CustomAttrPrefix = "NRI_"
+	// CounterResetAttr is the dimension added to the cumulative counts detected as reset, see CounterResetDetection.
+	CounterResetAttr = "counter.reset"
 )
 
+// CounterResetEventCategory is the category of the events emitted for reset cumulative counts.
+const CounterResetEventCategory = "counter-reset"
+
 // NR infrastructure agent protocol version
 const (
 	protocolVersion = "4"
@@ -44,12 +51,36 @@ type Integration struct {
 	Metadata        Metadata  `json:"integration"`
 	Entities        []*Entity `json:"data"`
 	// HostEntity is an "entity" that serves as dumping ground for metrics not associated with a specific entity
-	HostEntity   *Entity `json:"-"` //skip json serializing
-	locker       sync.Locker
-	prettyOutput bool
-	writer       io.Writer
-	logger       log.Logger
-	args         interface{}
+	HostEntity    *Entity `json:"-"` //skip json serializing
+	locker        sync.Locker
+	prettyOutput  bool
+	strictMetrics bool
+	writer        io.Writer
+	logger        log.Logger
+	args          interface{}
+	// where the arguments are parsed from, see IsolatedArgs
+	argsOptions []args.SetupOption
+	environ     func() []string
+	// environment variables bound to the arguments, never added as tags, see argumentsEnv
+	argsEnv map[string]bool
+	// subcommands of the binary and the selected one, see Commands
+	commands []args.Command
+	command  string
+	// client side deltas and rates computation, see ClientSideDeltas
+	deltaCalculator *metric.DeltaCalculator
+	deltaMetrics    map[string]bool
+	// cumulative counts reset detection, see CounterResetDetection
+	resetDetector *metric.ResetDetector
+	resetActions  CounterResetAction
+	rollups       []Rollup
+	// metrics declaration, see MetricCatalog
+	catalog       *metric.Catalog
+	unitAttribute string
+	// metrics emitted with conflicting types or units, see MetricConflictDetection
+	detectConflicts bool
+	conflictUnit    string
+	// handling of NaN and infinite values, see NonFiniteValues
+	nonFinitePolicy metric.NonFinitePolicy
 }
 
 // New creates new integration with sane default values.
@@ -72,6 +103,7 @@ func New(name, version string, opts ...Option) (i *Integration, err error) {
 		Entities:        []*Entity{},
 		writer:          os.Stdout,
 		locker:          &sync.Mutex{},
+		environ:         os.Environ,
 	}
 
 	for _, opt := range opts {
@@ -82,14 +114,16 @@ func New(name, version string, opts ...Option) (i *Integration, err error) {
 	}
 
 	// arguments
-	if err = i.checkArguments(); err != nil {
-		return
-	}
-	if err = args.SetupArgs(i.args); err != nil {
+	if err = i.setupArguments(); err != nil {
 		return
 	}
 	defaultArgs := args.GetDefaultArgs(i.args)
 	i.prettyOutput = defaultArgs.Pretty
+	if defaultArgs.Metadata {
+		if i.argsEnv, err = i.argumentsEnv(); err != nil {
+			return
+		}
+	}
 
 	if defaultArgs.Verbose {
 		log.SetupLogging(defaultArgs.Verbose)
@@ -105,6 +139,13 @@ func New(name, version string, opts ...Option) (i *Integration, err error) {
 	return
 }
 
+// SetTimestampPrecision sets the precision of the timestamps of the metrics, events and common blocks created
+// from now on (time.Second by default). It's a process-wide setting, shared by every integration in the process,
+// as metrics and events are created independently of them, see the precision package.
+func SetTimestampPrecision(p time.Duration) error {
+	return precision.Set(p)
+}
+
 // NewEntity method creates a new (uniquely named) Entity.
 // The `name` of the Entity must be unique for the account otherwise it will cause conflicts
 func (i *Integration) NewEntity(name string, entityType string, displayName string) (e *Entity, err error) {
@@ -139,18 +180,64 @@ func (i *Integration) Publish() error {
 		}
 	}
 
+	if err := i.computeRollups(); err != nil {
+		return err
+	}
+
 	// add the host entity to the list of entities to be serialized, if not empty
 	if notEmpty(i.HostEntity) {
 		i.Entities = append(i.Entities, i.HostEntity)
 	}
+
+	if i.catalog != nil {
+		i.checkCatalog()
+	}
+
+	if i.detectConflicts {
+		if err := i.checkConflicts(); err != nil {
+			if i.strictMetrics {
+				return err
+			}
+			i.logger.Warnf("%s", err)
+		}
+	}
+
+	var resets map[metric.Metric]bool
+	if i.resetDetector != nil {
+		resets = i.detectCounterResets()
+	}
+
+	if i.deltaCalculator != nil {
+		i.computeDeltas(resets)
+	}
+
+	if i.resetActions&CounterResetAttribute != 0 {
+		for m := range resets {
+			_ = m.AddTypedDimension(CounterResetAttr, true)
+		}
+	}
+
+	if err := i.applyNonFinitePolicy(); err != nil {
+		return err
+	}
+
+	if i.strictMetrics {
+		if err := i.normalizeMetrics(); err != nil {
+			return err
+		}
+	}
+
 	output, err := i.toJSON(i.prettyOutput)
 	if err != nil {
 		return err
 	}
 	output = append(output, []byte{'\n'}...)
-	_, err = i.writer.Write(output)
+	if _, err = i.writer.Write(output); err != nil {
+		return err
+	}
 
-	return err
+	i.saveState()
+	return nil
 }
 
 // Clear re-initializes the Inventory, Metrics and Events for this integration.
@@ -161,6 +248,7 @@ func (i *Integration) Clear() {
 	i.Entities = []*Entity{} // empty array preferred instead of null on marshaling.
 	// reset the host entity
 	i.HostEntity = newHostEntity()
+	i.rollups = nil
 }
 
 // MarshalJSON serializes integration to JSON, fulfilling Marshaler interface.
@@ -194,6 +282,12 @@ func (i *Integration) Logger() log.Logger {
 	return i.logger
 }
 
+// Command returns the name of the command selected from the command-line, see Commands. It's empty if the
+// integration has no commands.
+func (i *Integration) Command() string {
+	return i.command
+}
+
 // FindEntity finds ad return an entity by name. returns false if entity does not exist in the integration
 func (i *Integration) FindEntity(name string) (*Entity, bool) {
 	if i.Entities == nil {
@@ -253,12 +347,243 @@ func PrometheusSummary(timestamp time.Time, metricName string, sampleCount uint6
 	return metric.NewPrometheusSummary(timestamp, metricName, sampleCount, sampleSum)
 }
 
+// ExponentialHistogram creates a metric of type exponential histogram
+func ExponentialHistogram(timestamp time.Time, metricName string, scale int32, sampleCount uint64, sampleSum float64) (*metric.ExponentialHistogram, error) {
+	return metric.NewExponentialHistogram(timestamp, metricName, scale, sampleCount, sampleSum)
+}
+
 // -- private
 // is entity empty?
 func notEmpty(entity *Entity) bool {
 	return len(entity.Events) > 0 || len(entity.Metrics) > 0 || entity.Inventory.Len() > 0
 }
 
+// computeDeltas replaces the selected cumulative metrics by their client side computed deltas and rates.
+// Metrics whose value can't be computed (i.e. on the first run) are not published. Metrics detected as reset
+// are tracked on their converted counterparts too.
+func (i *Integration) computeDeltas(resets map[metric.Metric]bool) {
+	for _, e := range i.Entities {
+		namespace := "host"
+		if !e.isHostEntity() {
+			namespace = e.Name()
+		}
+
+		metrics := make(metric.Metrics, 0, len(e.Metrics))
+		for _, m := range e.Metrics {
+			if len(i.deltaMetrics) > 0 && !i.deltaMetrics[m.GetName()] {
+				metrics = append(metrics, m)
+				continue
+			}
+			converted, err := i.deltaCalculator.Convert(namespace, m)
+			if errors.Is(err, metric.ErrNoPreviousValue) {
+				i.logger.Debugf("%s, skipping it", err)
+				continue
+			}
+			if err != nil {
+				i.logger.Warnf("%s, skipping it", err)
+				continue
+			}
+			if resets[m] {
+				resets[converted] = true
+			}
+			metrics = append(metrics, converted)
+		}
+		e.Metrics = metrics
+	}
+}
+
+// checkCatalog warns about the metrics not matching their declaration in the catalog, once per metric name,
+// and adds the declared units to the metrics if configured.
+func (i *Integration) checkCatalog() {
+	warned := map[string]bool{}
+	for _, e := range i.Entities {
+		for _, m := range e.Metrics {
+			if err := i.catalog.Check(m); err != nil && !warned[m.GetName()] {
+				warned[m.GetName()] = true
+				i.logger.Warnf("%s", err)
+			}
+			if i.unitAttribute == "" {
+				continue
+			}
+			if d, ok := i.catalog.Lookup(m.GetName()); ok && d.Unit != "" {
+				_ = m.AddDimension(i.unitAttribute, d.Unit)
+			}
+		}
+	}
+}
+
+// metricOrigin records how a metric name was first emitted in the payload.
+type metricOrigin struct {
+	sourceType metric.SourceType
+	unit       string
+	entity     string
+}
+
+// checkConflicts returns an error listing the metric names emitted with different source types, or with
+// different values of the unit dimension if configured, across all the entities to be published.
+func (i *Integration) checkConflicts() error {
+	origins := map[string]metricOrigin{}
+	reported := map[string]bool{}
+	var conflicts []string
+	for _, e := range i.Entities {
+		entity := "host"
+		if !e.isHostEntity() {
+			entity = e.Name()
+		}
+
+		for _, m := range e.Metrics {
+			current := metricOrigin{sourceType: m.GetType(), entity: entity}
+			if i.conflictUnit != "" {
+				current.unit = m.Dimension(i.conflictUnit)
+			}
+
+			first, ok := origins[m.GetName()]
+			if !ok {
+				origins[m.GetName()] = current
+				continue
+			}
+			if reported[m.GetName()] {
+				continue
+			}
+			if first.sourceType != current.sourceType {
+				reported[m.GetName()] = true
+				conflicts = append(conflicts, fmt.Sprintf("metric %s emitted as %s in entity %s and as %s in entity %s",
+					m.GetName(), first.sourceType, first.entity, current.sourceType, current.entity))
+			} else if first.unit != current.unit {
+				reported[m.GetName()] = true
+				conflicts = append(conflicts, fmt.Sprintf("metric %s emitted with unit %q in entity %s and %q in entity %s",
+					m.GetName(), first.unit, first.entity, current.unit, current.entity))
+			}
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("conflicting metrics: %s", strings.Join(conflicts, "; "))
+}
+
+// detectCounterResets checks the cumulative counts of all the entities to be published against their values in
+// the previous run, logging a warning and emitting an event, if enabled, for every decreasing one.
+// It returns the set of reset metrics.
+func (i *Integration) detectCounterResets() map[metric.Metric]bool {
+	resets := map[metric.Metric]bool{}
+	for _, e := range i.Entities {
+		namespace := "host"
+		if !e.isHostEntity() {
+			namespace = e.Name()
+		}
+
+		for _, m := range e.Metrics {
+			previous, reset, err := i.resetDetector.Check(namespace, m)
+			if err != nil {
+				i.logger.Warnf("can't check cumulative count %s for resets: %s", m.GetName(), err)
+				continue
+			}
+			if !reset {
+				continue
+			}
+			current := m.(metric.NumericMetric).GetValue()
+
+			resets[m] = true
+			i.logger.Warnf("cumulative count %s of entity %s decreased from %v to %v, counter reset assumed",
+				m.GetName(), namespace, previous, current)
+
+			if i.resetActions&CounterResetEvent != 0 {
+				ev, err := event.New(m.GetTimestamp(), fmt.Sprintf("Counter %s reset", m.GetName()), CounterResetEventCategory)
+				if err != nil {
+					continue
+				}
+				_ = ev.AddAttribute("metricName", m.GetName())
+				_ = ev.AddAttribute("previousValue", previous)
+				_ = ev.AddAttribute("currentValue", current)
+				e.AddEvent(ev)
+			}
+		}
+	}
+	return resets
+}
+
+// saveState persists the values kept to compute deltas and detect counter resets in the next run. It's called
+// once the payload is written, so the next run doesn't compare against values that were never published.
+func (i *Integration) saveState() {
+	if i.resetDetector != nil {
+		if err := i.resetDetector.Save(); err != nil {
+			i.logger.Errorf("can't save the values to detect counter resets in the next run: %s", err)
+		}
+	}
+	if i.deltaCalculator != nil {
+		if err := i.deltaCalculator.Save(); err != nil {
+			i.logger.Errorf("can't save the values to compute deltas in the next run: %s", err)
+		}
+	}
+}
+
+// applyNonFinitePolicy applies the configured policy to the metrics holding NaN or infinite values of all the
+// entities to be published.
+func (i *Integration) applyNonFinitePolicy() error {
+	for _, e := range i.Entities {
+		metrics := make(metric.Metrics, 0, len(e.Metrics))
+		for _, m := range e.Metrics {
+			keep, err := i.nonFinitePolicy.Apply(m)
+			if err != nil {
+				if e.isHostEntity() {
+					return fmt.Errorf("invalid metric in host entity: %s", err)
+				}
+				return fmt.Errorf("invalid metric in entity %s: %s", e.Name(), err)
+			}
+			if !keep {
+				i.logger.Warnf("metric %s has non-finite values, skipping it", m.GetName())
+				continue
+			}
+			metrics = append(metrics, m)
+		}
+		e.Metrics = metrics
+	}
+	return nil
+}
+
+// normalizeMetrics validates and normalizes the metrics of all the entities to be published.
+func (i *Integration) normalizeMetrics() error {
+	for _, e := range i.Entities {
+		for _, m := range e.Metrics {
+			n, ok := m.(metric.Normalizer)
+			if !ok {
+				continue
+			}
+			if err := n.Normalize(); err != nil {
+				if e.isHostEntity() {
+					return fmt.Errorf("invalid metric in host entity: %s", err)
+				}
+				return fmt.Errorf("invalid metric in entity %s: %s", e.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// setupArguments populates the arguments, or the arguments of the selected command if the integration has
+// commands.
+func (i *Integration) setupArguments() error {
+	if len(i.commands) == 0 {
+		if err := i.checkArguments(); err != nil {
+			return err
+		}
+		return args.SetupArgs(i.args, i.argsOptions...)
+	}
+
+	if i.args != nil {
+		return errors.New("arguments can't be set along with commands")
+	}
+	cmd, err := args.SetupCommand(i.commands, i.argsOptions...)
+	if err != nil {
+		return err
+	}
+	i.command = cmd.Name
+	i.args = cmd.Args
+	return nil
+}
+
 func (i *Integration) checkArguments() error {
 	if i.args == nil {
 		i.args = new(struct{})
@@ -278,10 +603,10 @@ func (i *Integration) addDefaultAttributes(e *Entity) error {
 
 	// get env vars values for "custom" prefixed vars (NRIA_) and add them as attributes to the entity
 	if defaultArgs.Metadata {
-		for _, element := range os.Environ() {
+		for _, element := range i.environ() {
 			variable := strings.Split(element, "=")
 			prefix := fmt.Sprintf("%s%s_", CustomAttrPrefix, strings.ToUpper(i.Metadata.Name))
-			if strings.HasPrefix(variable[0], prefix) {
+			if strings.HasPrefix(variable[0], prefix) && !i.argsEnv[variable[0]] {
 				err := e.AddTag(strings.TrimPrefix(variable[0], prefix), variable[1])
 				if err != nil {
 					return err
@@ -293,6 +618,25 @@ func (i *Integration) addDefaultAttributes(e *Entity) error {
 	return nil
 }
 
+// argumentsEnv returns the environment variables the arguments are read from, along with their _FILE variants.
+// They can share the prefix of the custom attributes, e.g. NRI_MYSQL_PASSWORD for the "nri-mysql" environment
+// prefix, but must never be added as tags.
+func (i *Integration) argumentsEnv() (map[string]bool, error) {
+	bound := map[string]bool{}
+	if i.args == nil {
+		return bound, nil
+	}
+	arguments, err := args.Describe(i.args, i.argsOptions...)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range arguments {
+		bound[a.EnvName] = true
+		bound[a.EnvName+"_FILE"] = true
+	}
+	return bound, nil
+}
+
 // ReplaceLocalhost replaces the occurrence of a localhost address with
 // the given hostname. This is done to avoid entity identifier collision.
 func replaceLocalhost(source, with string) string {
@@ -1,9 +1,15 @@
 package integration
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 
+	"github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
 	"github.com/newrelic/infra-integrations-sdk/v4/log"
+	"github.com/newrelic/infra-integrations-sdk/v4/persist"
 )
 
 // Option sets an option on integration level.
@@ -35,3 +41,159 @@ func Args(a interface{}) Option {
 		return nil
 	}
 }
+
+// Commands sets the commands (modes) of the integration binary, each with its own arguments, instead of the Args
+// option. The command is selected from the first command-line argument (see args.SetupCommand), and its name
+// is returned by Integration.Command.
+func Commands(commands ...args.Command) Option {
+	return func(i *Integration) error {
+		i.commands = commands
+
+		return nil
+	}
+}
+
+// IsolatedArgs parses the arguments in a dedicated flag set, from the given command-line arguments (without the
+// program name) and environment variables (in the "KEY=value" form returned by os.Environ), instead of using
+// flag.CommandLine, os.Args and the process environment. This allows creating several integrations in the same
+// process, e.g. in tests.
+func IsolatedArgs(argv []string, environ []string) Option {
+	return func(i *Integration) error {
+		i.argsOptions = append(i.argsOptions,
+			args.FlagSet(flag.NewFlagSet(i.Metadata.Name, flag.ContinueOnError)),
+			args.Argv(argv),
+			args.Environ(environ),
+		)
+		i.environ = func() []string {
+			return environ
+		}
+
+		return nil
+	}
+}
+
+// SecretCommands enables the ${cmd:command args} secret references in the arguments, which are replaced by the
+// output of the command.
+func SecretCommands() Option {
+	return func(i *Integration) error {
+		i.argsOptions = append(i.argsOptions, args.SecretCommands())
+
+		return nil
+	}
+}
+
+// ArgsEnvPrefix reads the arguments from the environment variables with the given prefix, e.g. the
+// integration name, so NRI_MYSQL_PASSWORD sets the password argument for the "nri-mysql" prefix. If
+// legacyLookup is true, the arguments not set from prefixed variables are read from the variables
+// with their upper-cased name, e.g. PASSWORD. Variables bound to arguments are never added as entity tags by
+// the metadata argument, even if they share its NRI_<NAME>_ prefix.
+func ArgsEnvPrefix(prefix string, legacyLookup bool) Option {
+	return func(i *Integration) error {
+		i.argsOptions = append(i.argsOptions, args.EnvPrefix(prefix))
+		if legacyLookup {
+			i.argsOptions = append(i.argsOptions, args.LegacyEnv())
+		}
+
+		return nil
+	}
+}
+
+// StrictMetrics enables the validation of the metrics before they are published. Metrics that
+// can be normalized (i.e. Prometheus histograms and summaries) are put in a canonical form, and
+// publishing fails if any of them is invalid.
+func StrictMetrics() Option {
+	return func(i *Integration) error {
+		i.strictMetrics = true
+
+		return nil
+	}
+}
+
+// ClientSideDeltas makes the integration compute the values of cumulative metrics before publishing them,
+// instead of leaving it to the agent, which is useful when the output is not consumed by the agent.
+// Cumulative-count metrics are published as counts with the delta since the previous run, and cumulative-rate
+// metrics as gauges with the per-second rate since the previous run. Values are kept between runs in the storer.
+// Only the metrics with the given names are converted, or all of them if no name is provided.
+// Metrics are not published on the first run, as there is no previous value to compare with.
+func ClientSideDeltas(storer persist.Storer, metricNames ...string) Option {
+	return func(i *Integration) error {
+		if storer == nil {
+			return errors.New("storer cannot be nil")
+		}
+		i.deltaCalculator = metric.NewDeltaCalculator(storer)
+		i.deltaMetrics = make(map[string]bool, len(metricNames))
+		for _, name := range metricNames {
+			i.deltaMetrics[name] = true
+		}
+
+		return nil
+	}
+}
+
+// CounterResetAction defines what to do, besides logging a warning, when a cumulative count reset is detected.
+// Actions can be combined.
+type CounterResetAction int
+
+// Counter reset actions
+const (
+	// CounterResetAttribute adds the CounterResetAttr dimension to the reset metric.
+	CounterResetAttribute CounterResetAction = 1 << iota
+	// CounterResetEvent adds an event of category CounterResetEventCategory to the entity of the reset metric.
+	CounterResetEvent
+)
+
+// CounterResetDetection enables the tracking of cumulative counts between runs, keeping their values in the
+// storer, to detect when they decrease. A warning is logged for every reset counter, and the given actions are
+// applied on it.
+func CounterResetDetection(storer persist.Storer, actions CounterResetAction) Option {
+	return func(i *Integration) error {
+		if storer == nil {
+			return errors.New("storer cannot be nil")
+		}
+		i.resetDetector = metric.NewResetDetector(storer)
+		i.resetActions = actions
+
+		return nil
+	}
+}
+
+// MetricCatalog sets the catalog the emitted metrics are checked against before publishing. A warning is logged
+// for every undeclared metric, metric emitted with a type different from the declared one or with undeclared
+// dimensions. If unitAttribute is not empty, the declared unit of every metric is added as a dimension with that
+// name.
+func MetricCatalog(c *metric.Catalog, unitAttribute string) Option {
+	return func(i *Integration) error {
+		if c == nil {
+			return errors.New("catalog cannot be nil")
+		}
+		i.catalog = c
+		i.unitAttribute = unitAttribute
+
+		return nil
+	}
+}
+
+// MetricConflictDetection enables checking, before publishing, that every metric name is emitted with the same
+// source type across the whole payload and, if unitAttribute is not empty, with the same value of the dimension
+// with that name. Conflicts are logged as a warning, or make publishing fail if StrictMetrics is enabled.
+func MetricConflictDetection(unitAttribute string) Option {
+	return func(i *Integration) error {
+		i.detectConflicts = true
+		i.conflictUnit = unitAttribute
+
+		return nil
+	}
+}
+
+// NonFiniteValues sets how the metrics holding NaN or infinite values are handled before publishing: published
+// with null values (the default), dropped, published with zero values, or making publishing fail.
+func NonFiniteValues(p metric.NonFinitePolicy) Option {
+	return func(i *Integration) error {
+		if p.String() == "" {
+			return fmt.Errorf("unknown non-finite values policy %d", p)
+		}
+		i.nonFinitePolicy = p
+
+		return nil
+	}
+}
@@ -5,13 +5,21 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/newrelic/infra-integrations-sdk/v4/args"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v4/data/precision"
 	"github.com/newrelic/infra-integrations-sdk/v4/log"
+	"github.com/newrelic/infra-integrations-sdk/v4/persist"
 )
 
 func Test_PublishWritesUsingSelectedWriter(t *testing.T) {
@@ -129,10 +137,9 @@ func Test_CustomArgumentsAreAddedToArgumentList(t *testing.T) {
 }
 
 func Test_DefaultArguments(t *testing.T) {
-	t.Skip("This is failing to due to flag redefinition. We'll take a look later")
 	al := args.DefaultArgumentList{}
 
-	i, err := New("TestIntegration", "1.0", Logger(log.Discard), Writer(ioutil.Discard), Args(&al))
+	i, err := New("TestIntegration", "1.0", Logger(log.Discard), Writer(ioutil.Discard), Args(&al), IsolatedArgs(nil, nil))
 	assert.NoError(t, err)
 
 	assert.Equal(t, "TestIntegration", i.Metadata.Name)
@@ -144,6 +151,105 @@ func Test_DefaultArguments(t *testing.T) {
 	assert.False(t, al.Verbose)
 }
 
+func Test_IsolatedArgsAllowSeveralIntegrations(t *testing.T) {
+	type argumentList struct {
+		args.DefaultArgumentList
+		Hostname string `default:"localhost" help:""`
+	}
+
+	os.Args = []string{"cmd", "-hostname=os-args-host"}
+	flag.CommandLine = flag.NewFlagSet("cmd", flag.ContinueOnError)
+
+	var al1, al2 argumentList
+	_, err := New("integration", "1.0", Logger(log.Discard), Args(&al1),
+		IsolatedArgs([]string{"-hostname=host1"}, nil))
+	assert.NoError(t, err)
+	i2, err := New("integration", "1.0", Logger(log.Discard), Args(&al2),
+		IsolatedArgs([]string{"-pretty"}, []string{"HOSTNAME=host2", "METADATA=true", "NRI_INTEGRATION_TEAM=core"}))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "host1", al1.Hostname)
+	assert.False(t, al1.Pretty)
+	assert.Equal(t, "host2", al2.Hostname)
+	assert.True(t, al2.Pretty)
+	assert.Nil(t, flag.CommandLine.Lookup("hostname"), "global flag set is untouched")
+
+	e, err := i2.NewEntity("entity", "test", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "core", e.GetMetadata()["tags.TEAM"])
+}
+
+func Test_ArgsEnvPrefix(t *testing.T) {
+	type argumentList struct {
+		args.DefaultArgumentList
+		Hostname string `default:"localhost" help:""`
+		Port     int    `default:"3306" help:""`
+	}
+	environ := []string{"NRI_MYSQL_HOSTNAME=prefixed", "HOSTNAME=legacy", "PORT=1234"}
+
+	var al argumentList
+	_, err := New("nri-mysql", "1.0", Logger(log.Discard), Args(&al),
+		IsolatedArgs(nil, environ), ArgsEnvPrefix("nri-mysql", false))
+	assert.NoError(t, err)
+	assert.Equal(t, "prefixed", al.Hostname)
+	assert.Equal(t, 3306, al.Port)
+
+	var legacy argumentList
+	_, err = New("nri-mysql", "1.0", Logger(log.Discard), Args(&legacy),
+		IsolatedArgs(nil, environ), ArgsEnvPrefix("nri-mysql", true))
+	assert.NoError(t, err)
+	assert.Equal(t, "prefixed", legacy.Hostname)
+	assert.Equal(t, 1234, legacy.Port)
+}
+
+func Test_ArgsEnvPrefixVariablesAreNotAddedAsTags(t *testing.T) {
+	type argumentList struct {
+		args.DefaultArgumentList
+		Password string `help:""`
+	}
+	environ := []string{"NRI_MYSQL_PASSWORD=s3cr3t", "NRI_MYSQL_PASSWORD_FILE=/run/secrets/password", "NRI_MYSQL_TEAM=core"}
+
+	var al argumentList
+	i, err := New("mysql", "1.0", Logger(log.Discard), Args(&al),
+		IsolatedArgs([]string{"-metadata"}, environ), ArgsEnvPrefix("nri-mysql", false))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", al.Password)
+
+	e, err := i.NewEntity("db", "database", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "core", e.Metadata.GetTag("TEAM"))
+	assert.Nil(t, e.Metadata.GetTag("PASSWORD"))
+	assert.Nil(t, e.Metadata.GetTag("PASSWORD_FILE"))
+}
+
+func Test_CommandsSetUpTheSelectedCommandArguments(t *testing.T) {
+	type collectArgs struct {
+		args.DefaultArgumentList
+		Hostname string `default:"localhost" help:""`
+	}
+	var collect collectArgs
+	commands := []args.Command{
+		{Name: "collect", Args: &collect},
+		{Name: "version"},
+	}
+
+	i, err := New("integration", "1.0", Logger(log.Discard), Commands(commands...),
+		IsolatedArgs([]string{"collect", "-hostname=db", "-pretty"}, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "collect", i.Command())
+	assert.Equal(t, "db", collect.Hostname)
+	assert.True(t, i.prettyOutput)
+
+	i, err = New("integration", "1.0", Logger(log.Discard), Commands(commands...),
+		IsolatedArgs([]string{"version"}, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "version", i.Command())
+
+	_, err = New("integration", "1.0", Logger(log.Discard), Commands(commands...), Args(&collect),
+		IsolatedArgs(nil, nil))
+	assert.Error(t, err)
+}
+
 func Test_DefaultArgsSetNonVerboseLogging(t *testing.T) {
 	type argumentList struct {
 		args.DefaultArgumentList
@@ -205,3 +311,330 @@ func Test_ClusterAndServiceArgumentsAreAddedToMetadata(t *testing.T) {
 
 	assert.Len(t, e.GetMetadata(), 0)
 }
+
+func Test_StrictMetricsNormalizesMetricsBeforePublishing(t *testing.T) {
+	var w bytes.Buffer
+	i, err := New("integration", "7.0", Writer(&w), StrictMetrics())
+	assert.NoError(t, err)
+
+	ph, _ := PrometheusHistogram(time.Unix(10000000, 0), "histogram", 2, 3)
+	ph.AddBucket(2, 2)
+	ph.AddBucket(1, 1)
+	i.HostEntity.AddMetric(ph)
+
+	assert.NoError(t, i.Publish())
+	assert.Contains(t, w.String(), `"buckets":[{"cumulative_count":1,"upper_bound":1},{"cumulative_count":2,"upper_bound":2}]`)
+}
+
+func Test_StrictMetricsRejectsInvalidMetrics(t *testing.T) {
+	var w bytes.Buffer
+	i, err := New("integration", "7.0", Writer(&w), StrictMetrics())
+	assert.NoError(t, err)
+
+	e, err := i.NewEntity("entity", "test", "")
+	assert.NoError(t, err)
+	ph, _ := PrometheusHistogram(time.Unix(10000000, 0), "histogram", 2, 3)
+	ph.AddBucket(2, 1)
+	ph.AddBucket(1, 2)
+	e.AddMetric(ph)
+	i.AddEntity(e)
+
+	err = i.Publish()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "entity")
+	assert.Empty(t, w.String())
+}
+
+func Test_SetTimestampPrecision(t *testing.T) {
+	defer func() { _ = precision.Set(time.Second) }()
+
+	assert.Error(t, SetTimestampPrecision(time.Minute))
+	assert.NoError(t, SetTimestampPrecision(time.Millisecond))
+
+	var w bytes.Buffer
+	i, err := New("integration", "7.0", Writer(&w))
+	assert.NoError(t, err)
+
+	ts := time.Unix(10000000, 123456789)
+	g, _ := Gauge(ts, "gauge", 1)
+	i.HostEntity.AddMetric(g)
+	i.HostEntity.AddCommonTimestamp(ts)
+	ev, _ := event.New(ts, "summary", "category")
+	i.HostEntity.AddEvent(ev)
+
+	assert.NoError(t, i.Publish())
+	assert.Contains(t, w.String(), `"common":{"timestamp":10000000123}`)
+	assert.Contains(t, w.String(), `{"timestamp":10000000123,"name":"gauge"`)
+	assert.Contains(t, w.String(), `{"timestamp":10000000123,"summary":"summary"`)
+}
+
+func Test_ClientSideDeltasComputesCumulativeMetrics(t *testing.T) {
+	storer := persist.NewInMemoryStore()
+	t0 := time.Unix(10000000, 0)
+
+	publish := func(ts time.Time, value float64) string {
+		var w bytes.Buffer
+		i, err := New("integration", "7.0", Writer(&w), Logger(log.Discard), ClientSideDeltas(storer, "requests"))
+		assert.NoError(t, err)
+
+		e, err := i.NewEntity("entity", "test", "")
+		assert.NoError(t, err)
+		cc, _ := CumulativeCount(ts, "requests", value)
+		e.AddMetric(cc)
+		other, _ := CumulativeCount(ts, "other", value)
+		e.AddMetric(other)
+		i.AddEntity(e)
+
+		assert.NoError(t, i.Publish())
+		return w.String()
+	}
+
+	// first run has no previous value
+	out := publish(t0, 100)
+	assert.NotContains(t, out, `"name":"requests"`)
+	assert.Contains(t, out, `"name":"other","type":"cumulative-count"`)
+
+	out = publish(t0.Add(10*time.Second), 150)
+	assert.Contains(t, out, `"name":"requests","type":"count","attributes":{},"value":50`)
+	assert.Contains(t, out, `"name":"other","type":"cumulative-count"`)
+}
+
+func Test_ClientSideDeltasDoesNotSaveValuesOfFailedPublish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deltas")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "store.json")
+	t0 := time.Unix(10000000, 0)
+
+	publish := func(ts time.Time, value float64, invalid bool) (string, error) {
+		storer, err := persist.NewFileStore(path, log.Discard, time.Hour)
+		assert.NoError(t, err)
+
+		var w bytes.Buffer
+		i, err := New("integration", "7.0", Writer(&w), Logger(log.Discard), StrictMetrics(),
+			ClientSideDeltas(storer))
+		assert.NoError(t, err)
+		cc, _ := CumulativeCount(ts, "requests", value)
+		i.HostEntity.AddMetric(cc)
+		if invalid {
+			h, _ := metric.NewPrometheusHistogram(ts, "histogram", 1, 1)
+			h.AddBucket(1, math.NaN())
+			i.HostEntity.AddMetric(h)
+		}
+		err = i.Publish()
+		return w.String(), err
+	}
+
+	_, err = publish(t0, 100, false)
+	assert.NoError(t, err)
+	_, err = publish(t0.Add(10*time.Second), 500, true)
+	assert.Error(t, err)
+
+	out, err := publish(t0.Add(20*time.Second), 150, false)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"name":"requests","type":"count","attributes":{},"value":50`)
+}
+
+func Test_ClientSideDeltasRequiresStorer(t *testing.T) {
+	_, err := New("integration", "7.0", ClientSideDeltas(nil))
+	assert.Error(t, err)
+}
+
+func Test_CounterResetDetectionMarksResetCounters(t *testing.T) {
+	storer := persist.NewInMemoryStore()
+	var logs bytes.Buffer
+
+	publish := func(value float64) string {
+		var w bytes.Buffer
+		i, err := New("integration", "7.0", Writer(&w), Logger(log.New(false, &logs)),
+			CounterResetDetection(storer, CounterResetAttribute|CounterResetEvent))
+		assert.NoError(t, err)
+
+		e, err := i.NewEntity("router", "test", "")
+		assert.NoError(t, err)
+		cc, _ := CumulativeCount(time.Unix(10000000, 0), "packets", value)
+		e.AddMetric(cc)
+		i.AddEntity(e)
+
+		assert.NoError(t, i.Publish())
+		return w.String()
+	}
+
+	out := publish(100)
+	assert.NotContains(t, out, CounterResetAttr)
+
+	out = publish(10)
+	assert.Contains(t, out, `"attributes":{"counter.reset":true},"value":10`)
+	assert.Contains(t, out, `"summary":"Counter packets reset","category":"counter-reset"`)
+	assert.Contains(t, logs.String(), "cumulative count packets of entity router decreased from 100 to 10")
+
+	out = publish(20)
+	assert.NotContains(t, out, CounterResetAttr)
+}
+
+func Test_CounterResetDetectionWithClientSideDeltas(t *testing.T) {
+	storer := persist.NewInMemoryStore()
+	t0 := time.Unix(10000000, 0)
+
+	publish := func(ts time.Time, value float64) string {
+		var w bytes.Buffer
+		i, err := New("integration", "7.0", Writer(&w), Logger(log.Discard),
+			CounterResetDetection(storer, CounterResetAttribute), ClientSideDeltas(storer))
+		assert.NoError(t, err)
+
+		cc, _ := CumulativeCount(ts, "packets", value)
+		i.HostEntity.AddMetric(cc)
+
+		assert.NoError(t, i.Publish())
+		return w.String()
+	}
+
+	publish(t0, 100)
+	out := publish(t0.Add(time.Minute), 10)
+	assert.Contains(t, out, `"type":"count","attributes":{"counter.reset":true},"value":10`)
+}
+
+func Test_MetricCatalogWarnsAndAddsUnits(t *testing.T) {
+	c := metric.NewCatalog()
+	assert.NoError(t, c.Declare(
+		metric.Definition{Name: "db.queries", Type: metric.COUNT, Unit: "queries"},
+		metric.Definition{Name: "db.size", Type: metric.GAUGE, Unit: "bytes"},
+	))
+
+	var w, logs bytes.Buffer
+	i, err := New("integration", "7.0", Writer(&w), Logger(log.New(false, &logs)), MetricCatalog(c, "unit"))
+	assert.NoError(t, err)
+
+	queries, _ := Count(time.Unix(10000000, 0), "db.queries", 1)
+	i.HostEntity.AddMetric(queries)
+	size, _ := Count(time.Unix(10000000, 0), "db.size", 1)
+	i.HostEntity.AddMetric(size)
+	for n := 0; n < 2; n++ {
+		undeclared, _ := Gauge(time.Unix(10000000, 0), "db.undeclared", 1)
+		i.HostEntity.AddMetric(undeclared)
+	}
+
+	assert.NoError(t, i.Publish())
+	assert.Contains(t, w.String(), `"name":"db.queries","type":"count","attributes":{"unit":"queries"}`)
+	assert.Contains(t, w.String(), `"name":"db.undeclared","type":"gauge","attributes":{}`)
+	assert.Contains(t, logs.String(), "metric db.size is declared as gauge but emitted as count")
+	assert.Equal(t, 1, strings.Count(logs.String(), "metric db.undeclared is not declared"))
+}
+
+func newConflictingIntegration(t *testing.T, opts ...Option) (*Integration, *bytes.Buffer, *bytes.Buffer) {
+	var w, logs bytes.Buffer
+	i, err := New("integration", "7.0", append([]Option{Writer(&w), Logger(log.New(false, &logs))}, opts...)...)
+	assert.NoError(t, err)
+
+	e, err := i.NewEntity("entity", "test", "")
+	assert.NoError(t, err)
+	requests, _ := Count(time.Unix(10000000, 0), "requests", 1)
+	e.AddMetric(requests)
+	latency, _ := Gauge(time.Unix(10000000, 0), "latency", 1)
+	_ = latency.AddDimension("unit", "ms")
+	e.AddMetric(latency)
+	i.AddEntity(e)
+
+	hostRequests, _ := Gauge(time.Unix(10000000, 0), "requests", 1)
+	i.HostEntity.AddMetric(hostRequests)
+	hostLatency, _ := Gauge(time.Unix(10000000, 0), "latency", 1)
+	_ = hostLatency.AddDimension("unit", "s")
+	i.HostEntity.AddMetric(hostLatency)
+
+	return i, &w, &logs
+}
+
+func Test_MetricConflictDetectionWarns(t *testing.T) {
+	i, w, logs := newConflictingIntegration(t, MetricConflictDetection("unit"))
+
+	assert.NoError(t, i.Publish())
+	assert.NotEmpty(t, w.String())
+	assert.Contains(t, logs.String(), "metric requests emitted as count in entity entity and as gauge in entity host")
+	assert.Contains(t, logs.String(), `metric latency emitted with unit "ms" in entity entity and "s" in entity host`)
+}
+
+func Test_MetricConflictDetectionIgnoresUnitsIfNotConfigured(t *testing.T) {
+	i, _, logs := newConflictingIntegration(t, MetricConflictDetection(""))
+
+	assert.NoError(t, i.Publish())
+	assert.Contains(t, logs.String(), "metric requests emitted as count")
+	assert.NotContains(t, logs.String(), "latency")
+}
+
+func Test_MetricConflictDetectionRejectsInStrictMode(t *testing.T) {
+	i, w, _ := newConflictingIntegration(t, MetricConflictDetection("unit"), StrictMetrics())
+
+	err := i.Publish()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "metric requests emitted as count in entity entity and as gauge in entity host")
+	assert.Empty(t, w.String())
+}
+
+func Test_NonFiniteValuesPolicies(t *testing.T) {
+	publish := func(p metric.NonFinitePolicy) (string, error) {
+		var w bytes.Buffer
+		i, err := New("integration", "7.0", Writer(&w), Logger(log.New(false, ioutil.Discard)), NonFiniteValues(p))
+		assert.NoError(t, err)
+
+		nan, _ := Gauge(time.Unix(10000000, 0), "nan", math.NaN())
+		i.HostEntity.AddMetric(nan)
+		finite, _ := Gauge(time.Unix(10000000, 0), "finite", 1)
+		i.HostEntity.AddMetric(finite)
+
+		err = i.Publish()
+		return w.String(), err
+	}
+
+	out, err := publish(metric.NonFiniteNull)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"name":"nan","type":"gauge","attributes":{},"value":null`)
+
+	out, err = publish(metric.NonFiniteZero)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"name":"nan","type":"gauge","attributes":{},"value":0`)
+
+	out, err = publish(metric.NonFiniteDrop)
+	assert.NoError(t, err)
+	assert.NotContains(t, out, `"nan"`)
+	assert.Contains(t, out, `"finite"`)
+
+	out, err = publish(metric.NonFiniteError)
+	assert.EqualError(t, err, "invalid metric in host entity: metric nan has non-finite values: value")
+	assert.Empty(t, out)
+}
+
+func Test_NonFiniteValuesPoliciesOnExponentialHistograms(t *testing.T) {
+	publish := func(p metric.NonFinitePolicy) (string, error) {
+		var w bytes.Buffer
+		i, err := New("integration", "7.0", Writer(&w), Logger(log.New(false, ioutil.Discard)), NonFiniteValues(p))
+		assert.NoError(t, err)
+
+		eh, _ := metric.NewExponentialHistogram(time.Unix(10000000, 0), "latency", 0, 1, 1)
+		eh.Value.ZeroThreshold = math.Inf(1)
+		i.HostEntity.AddMetric(eh)
+
+		err = i.Publish()
+		return w.String(), err
+	}
+
+	out, err := publish(metric.NonFiniteNull)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"zero_threshold":null`)
+
+	out, err = publish(metric.NonFiniteZero)
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"zero_threshold":0`)
+
+	out, err = publish(metric.NonFiniteDrop)
+	assert.NoError(t, err)
+	assert.NotContains(t, out, `"latency"`)
+
+	out, err = publish(metric.NonFiniteError)
+	assert.EqualError(t, err, "invalid metric in host entity: metric latency has non-finite values: zero_threshold")
+	assert.Empty(t, out)
+}
+
+func Test_NonFiniteValuesRejectsUnknownPolicies(t *testing.T) {
+	_, err := New("integration", "7.0", NonFiniteValues(metric.NonFinitePolicy(42)))
+	assert.Error(t, err)
+}
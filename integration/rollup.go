@@ -0,0 +1,228 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+)
+
+// RollupFunc defines how the values of a metric are aggregated across entities.
+type RollupFunc int
+
+// Rollup functions
+const (
+	RollupSum RollupFunc = iota
+	RollupAvg
+	RollupMin
+	RollupMax
+)
+
+var rollupFuncNames = map[RollupFunc]string{
+	RollupSum: "sum",
+	RollupAvg: "avg",
+	RollupMin: "min",
+	RollupMax: "max",
+}
+
+// String fulfills stringer interface, returning empty string on invalid functions.
+func (f RollupFunc) String() string {
+	return rollupFuncNames[f]
+}
+
+// EntitySelector selects the entities a rollup is computed from.
+type EntitySelector struct {
+	// Type of the entities. Any type matches if empty.
+	Type string
+	// Metadata the entities must have, including tags with the "tags." prefix.
+	Metadata map[string]interface{}
+}
+
+// Rollup defines an aggregate of a metric across the entities matching a selector, which is written into a
+// target entity, e.g. the sum of the connections of all the nodes of a cluster into the cluster entity.
+// Only metrics holding a single numeric value (see metric.NumericMetric) are aggregated.
+type Rollup struct {
+	// Metric is the name of the aggregated metric.
+	Metric string
+	// Func is the aggregation function.
+	Func RollupFunc
+	// Selector selects the source entities.
+	Selector EntitySelector
+	// Target is the entity the result is written to. It's never used as a source.
+	Target *Entity
+	// Name of the resulting metric. Defaults to the metric name followed by the function, e.g. "connections.sum".
+	Name string
+	// GroupBy lists the dimensions of the source metrics the result is grouped by. A metric is written for
+	// every group, with these dimensions.
+	GroupBy []string
+}
+
+type rollupGroup struct {
+	dimensions map[string]interface{}
+	sourceType metric.SourceType
+	mixedTypes bool
+	timestamp  time.Time
+	count      int
+	result     float64
+}
+
+// AddRollup adds a rollup to be computed when the integration is published. Rollups are cleared after
+// publishing, as entities are.
+// The result of a sum keeps the type of the source metrics, while the rest of functions, or sums of metrics
+// of mixed types, result in gauges.
+func (i *Integration) AddRollup(r Rollup) error {
+	if len(r.Metric) == 0 {
+		return errors.New("rollup metric cannot be empty")
+	}
+	if r.Target == nil {
+		return errors.New("rollup target entity cannot be nil")
+	}
+	if _, ok := rollupFuncNames[r.Func]; !ok {
+		return fmt.Errorf("unknown rollup function %d", r.Func)
+	}
+	if r.Name == "" {
+		r.Name = r.Metric + "." + r.Func.String()
+	}
+
+	i.locker.Lock()
+	defer i.locker.Unlock()
+
+	i.rollups = append(i.rollups, r)
+	return nil
+}
+
+// computeRollups writes the result of every rollup into its target entity, adding the target to the
+// integration if needed.
+func (i *Integration) computeRollups() error {
+	for _, r := range i.rollups {
+		groups, keys := i.rollupGroups(r)
+		for _, k := range keys {
+			g := groups[k]
+			value := g.result
+			if r.Func == RollupAvg {
+				value /= float64(g.count)
+			}
+
+			m, err := rollupMetric(r, g, value)
+			if err != nil {
+				return err
+			}
+			for dim, v := range g.dimensions {
+				if err = m.AddTypedDimension(dim, v); err != nil {
+					return err
+				}
+			}
+			r.Target.AddMetric(m)
+		}
+
+		if r.Target != i.HostEntity && !i.hasEntity(r.Target) {
+			i.AddEntity(r.Target)
+		}
+	}
+	return nil
+}
+
+// rollupGroups aggregates the values of the rollup metric of the selected entities by group, returning the groups
+// along with their sorted keys.
+func (i *Integration) rollupGroups(r Rollup) (map[string]*rollupGroup, []string) {
+	groups := map[string]*rollupGroup{}
+	var keys []string
+
+	for _, e := range i.Entities {
+		if e == r.Target || !r.Selector.matches(e) {
+			continue
+		}
+		for _, m := range e.Metrics {
+			nm, ok := m.(metric.NumericMetric)
+			if !ok || m.GetName() != r.Metric {
+				continue
+			}
+
+			dims := map[string]interface{}{}
+			var sb strings.Builder
+			for _, d := range r.GroupBy {
+				if v := m.TypedDimension(d); v != nil {
+					dims[d] = v
+					sb.WriteString(fmt.Sprintf("%s=%v,", d, v))
+				}
+			}
+			key := sb.String()
+
+			g, ok := groups[key]
+			if !ok {
+				g = &rollupGroup{dimensions: dims, sourceType: m.GetType(), result: nm.GetValue()}
+				groups[key] = g
+				keys = append(keys, key)
+			} else {
+				g.result = r.Func.apply(g.result, nm.GetValue())
+			}
+			g.count++
+			g.mixedTypes = g.mixedTypes || g.sourceType != m.GetType()
+			if m.GetTimestamp().After(g.timestamp) {
+				g.timestamp = m.GetTimestamp()
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return groups, keys
+}
+
+func (f RollupFunc) apply(accumulated, value float64) float64 {
+	switch f {
+	case RollupMin:
+		return math.Min(accumulated, value)
+	case RollupMax:
+		return math.Max(accumulated, value)
+	default:
+		return accumulated + value
+	}
+}
+
+func rollupMetric(r Rollup, g *rollupGroup, value float64) (metric.Metric, error) {
+	if r.Func != RollupSum || g.mixedTypes {
+		return metric.NewGauge(g.timestamp, r.Name, value)
+	}
+
+	switch g.sourceType {
+	case metric.COUNT:
+		return metric.NewCount(g.timestamp, r.Name, value)
+	case metric.CUMULATIVE_COUNT:
+		return metric.NewCumulativeCount(g.timestamp, r.Name, value)
+	case metric.RATE:
+		return metric.NewRate(g.timestamp, r.Name, value)
+	case metric.CUMULATIVE_RATE:
+		return metric.NewCumulativeRate(g.timestamp, r.Name, value)
+	default:
+		return metric.NewGauge(g.timestamp, r.Name, value)
+	}
+}
+
+func (s EntitySelector) matches(e *Entity) bool {
+	if e.isHostEntity() {
+		return false
+	}
+	if s.Type != "" && e.Metadata.EntityType != s.Type {
+		return false
+	}
+	for k, v := range s.Metadata {
+		if !reflect.DeepEqual(e.Metadata.Metadata[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *Integration) hasEntity(e *Entity) bool {
+	for _, ie := range i.Entities {
+		if ie == e {
+			return true
+		}
+	}
+	return false
+}
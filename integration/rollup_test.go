@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/data/metric"
+)
+
+func newRollupTestIntegration(t *testing.T) (*Integration, *Entity) {
+	i := newTestIntegration(t)
+
+	nodes := []struct {
+		name    string
+		cluster string
+		role    string
+		conns   float64
+		lag     float64
+	}{
+		{"node1", "c1", "primary", 10, 1},
+		{"node2", "c1", "replica", 20, 5},
+		{"node3", "c1", "replica", 30, 3},
+		{"node4", "c2", "replica", 100, 50},
+	}
+	for _, n := range nodes {
+		e, err := i.NewEntity(n.name, "node", "")
+		require.NoError(t, err)
+		require.NoError(t, e.AddTag("cluster", n.cluster))
+
+		conns, _ := CumulativeCount(time.Unix(10000000, 0), "connections", n.conns)
+		_ = conns.AddDimension("role", n.role)
+		e.AddMetric(conns)
+		lag, _ := Gauge(time.Unix(10000000, 0), "lag", n.lag)
+		e.AddMetric(lag)
+		i.AddEntity(e)
+	}
+
+	cluster, err := i.NewEntity("c1", "cluster", "")
+	require.NoError(t, err)
+	return i, cluster
+}
+
+func TestIntegration_Rollups(t *testing.T) {
+	i, cluster := newRollupTestIntegration(t)
+	selector := EntitySelector{Type: "node", Metadata: map[string]interface{}{"tags.cluster": "c1"}}
+
+	require.NoError(t, i.AddRollup(Rollup{Metric: "connections", Func: RollupSum, Selector: selector, Target: cluster}))
+	require.NoError(t, i.AddRollup(Rollup{Metric: "lag", Func: RollupMax, Selector: selector, Target: cluster, Name: "max.lag"}))
+	require.NoError(t, i.AddRollup(Rollup{Metric: "lag", Func: RollupAvg, Selector: selector, Target: cluster}))
+	require.NoError(t, i.AddRollup(Rollup{Metric: "lag", Func: RollupMin, Selector: selector, Target: cluster}))
+	require.NoError(t, i.AddRollup(Rollup{
+		Metric: "connections", Func: RollupSum, Selector: selector, Target: cluster, Name: "connections.by_role",
+		GroupBy: []string{"role"},
+	}))
+
+	require.NoError(t, i.computeRollups())
+
+	assert.True(t, i.hasEntity(cluster), "target entity is added to the integration")
+	require.Len(t, cluster.Metrics, 6)
+
+	expected := []struct {
+		name       string
+		sourceType metric.SourceType
+		value      float64
+		role       string
+	}{
+		{"connections.sum", metric.CUMULATIVE_COUNT, 60, ""},
+		{"max.lag", metric.GAUGE, 5, ""},
+		{"lag.avg", metric.GAUGE, 3, ""},
+		{"lag.min", metric.GAUGE, 1, ""},
+		{"connections.by_role", metric.CUMULATIVE_COUNT, 10, "primary"},
+		{"connections.by_role", metric.CUMULATIVE_COUNT, 50, "replica"},
+	}
+	for n, e := range expected {
+		m := cluster.Metrics[n].(metric.NumericMetric)
+		assert.Equal(t, e.name, m.GetName())
+		assert.Equal(t, e.sourceType, m.GetType())
+		assert.Equal(t, e.value, m.GetValue())
+		assert.Equal(t, e.role, m.Dimension("role"))
+		assert.Equal(t, time.Unix(10000000, 0), m.GetTimestamp())
+	}
+}
+
+func TestIntegration_RollupsAreClearedOnPublish(t *testing.T) {
+	i, cluster := newRollupTestIntegration(t)
+
+	require.NoError(t, i.AddRollup(Rollup{Metric: "lag", Func: RollupMax, Target: cluster}))
+	require.NoError(t, i.Publish())
+
+	assert.Empty(t, i.rollups)
+}
+
+func TestIntegration_InvalidRollups(t *testing.T) {
+	i, cluster := newRollupTestIntegration(t)
+
+	assert.Error(t, i.AddRollup(Rollup{Func: RollupSum, Target: cluster}))
+	assert.Error(t, i.AddRollup(Rollup{Metric: "lag", Func: RollupSum}))
+	assert.Error(t, i.AddRollup(Rollup{Metric: "lag", Func: RollupFunc(42), Target: cluster}))
+}
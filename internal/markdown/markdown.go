@@ -0,0 +1,11 @@
+// Package markdown contains helpers shared by the SDK packages generating Markdown documentation.
+package markdown
+
+import "strings"
+
+var cellReplacer = strings.NewReplacer("|", "\\|", "\n", " ")
+
+// EscapeCell escapes the text so it can be placed in a Markdown table cell without breaking the table.
+func EscapeCell(s string) string {
+	return cellReplacer.Replace(s)
+}
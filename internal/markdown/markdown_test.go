@@ -0,0 +1,13 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeCell(t *testing.T) {
+	assert.Equal(t, "plain", EscapeCell("plain"))
+	assert.Equal(t, "open \\| idle", EscapeCell("open | idle"))
+	assert.Equal(t, "first line second line", EscapeCell("first line\nsecond line"))
+}
@@ -0,0 +1,184 @@
+// Package persist provides a simple key-value storage that integrations can use to keep data between executions.
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/log"
+)
+
+const (
+	// DefaultTTL specifies the "Time To Live" of the disk storage.
+	DefaultTTL = 1 * time.Minute
+
+	// directory under the temporary folder where the integrations data is stored
+	integrationsDir = "nr-integrations"
+)
+
+// ErrNotFound defines an error that will be returned when trying to access a storage entry that can't be found.
+var ErrNotFound = errors.New("key not found")
+
+// Storer defines the interface of a Key-Value storage system, which is able to store the timestamp
+// where the key was stored.
+type Storer interface {
+	// Set associates a value with a given key. Implementors must save also the time when it was stored and return it.
+	// The value can be any type.
+	Set(key string, value interface{}) int64
+	// Get gets the value associated to a given key and stores it in the value referenced by the pointer passed as
+	// argument. It returns the Unix timestamp when the value was stored (in seconds), or an error if the Get
+	// operation failed. It may return any type of value.
+	Get(key string, valuePtr interface{}) (int64, error)
+	// Delete removes the cached data for the given key. If the data does not exist, the system does not return
+	// any error.
+	Delete(key string) error
+	// Save persists all the data in the storage.
+	Save() error
+}
+
+type entry struct {
+	Timestamp int64           `json:"timestamp"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// inMemoryStore is a Storer implementation that keeps the data in memory.
+type inMemoryStore struct {
+	lock    sync.Mutex
+	entries map[string]entry
+	now     func() time.Time
+}
+
+// fileStore is a Storer implementation that persists the data in a file.
+type fileStore struct {
+	*inMemoryStore
+	path string
+	log  log.Logger
+}
+
+// NewInMemoryStore returns a Storer that keeps the data in memory, without persisting it.
+func NewInMemoryStore() Storer {
+	return newInMemoryStore()
+}
+
+// NewFileStore returns a disk-backed Storer using the provided file path. Data stored more than ttl ago is
+// discarded when the file is loaded.
+func NewFileStore(storagePath string, ilog log.Logger, ttl time.Duration) (Storer, error) {
+	if err := os.MkdirAll(filepath.Dir(storagePath), 0755); err != nil {
+		return nil, fmt.Errorf("can't create storage directory: %s", err)
+	}
+
+	fs := &fileStore{
+		inMemoryStore: newInMemoryStore(),
+		path:          storagePath,
+		log:           ilog,
+	}
+
+	content, err := ioutil.ReadFile(storagePath)
+	if os.IsNotExist(err) {
+		ilog.Debugf("storage file %s doesn't exist, starting with an empty storage", storagePath)
+		return fs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read storage file: %s", err)
+	}
+
+	var entries map[string]entry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		ilog.Warnf("storage file %s is corrupt, starting with an empty storage: %s", storagePath, err)
+		return fs, nil
+	}
+
+	oldest := fs.now().Add(-ttl).Unix()
+	for k, e := range entries {
+		if e.Timestamp < oldest {
+			continue
+		}
+		fs.entries[k] = e
+	}
+	return fs, nil
+}
+
+// DefaultPath returns a default storage path for the given integration name, in the temporary folder.
+func DefaultPath(integrationName string) string {
+	return filepath.Join(os.TempDir(), integrationsDir, integrationName+".json")
+}
+
+func newInMemoryStore() *inMemoryStore {
+	return &inMemoryStore{
+		entries: map[string]entry{},
+		now:     time.Now,
+	}
+}
+
+// Set stores a value for a given key, returning the Unix timestamp when it was stored.
+func (s *inMemoryStore) Set(key string, value interface{}) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		// values that can't be serialized can't be retrieved either
+		delete(s.entries, key)
+		return 0
+	}
+
+	ts := s.now().Unix()
+	s.entries[key] = entry{Timestamp: ts, Value: raw}
+	return ts
+}
+
+// Get reads the value stored for the given key into valuePtr, returning the Unix timestamp when it was stored.
+func (s *inMemoryStore) Get(key string, valuePtr interface{}) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if err := json.Unmarshal(e.Value, valuePtr); err != nil {
+		return 0, fmt.Errorf("can't read value for key %s: %s", key, err)
+	}
+	return e.Timestamp, nil
+}
+
+// Delete removes the value stored for the given key, if any.
+func (s *inMemoryStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Save does nothing, as data is only kept in memory.
+func (s *inMemoryStore) Save() error {
+	return nil
+}
+
+// Save writes all the stored data into the storage file.
+func (fs *fileStore) Save() error {
+	fs.lock.Lock()
+	content, err := json.Marshal(fs.entries)
+	fs.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a failed write doesn't corrupt the previous data
+	tmp := fs.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("can't write storage file: %s", err)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return fmt.Errorf("can't write storage file: %s", err)
+	}
+	fs.log.Debugf("storage saved to %s", fs.path)
+	return nil
+}
@@ -0,0 +1,101 @@
+package persist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infra-integrations-sdk/v4/log"
+)
+
+type sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+func TestInMemoryStore_SetGetDelete(t *testing.T) {
+	s := NewInMemoryStore()
+
+	var v sample
+	_, err := s.Get("key", &v)
+	assert.Equal(t, ErrNotFound, err)
+
+	ts := s.Set("key", sample{Value: 1.5, Timestamp: 10})
+	assert.NotZero(t, ts)
+
+	stored, err := s.Get("key", &v)
+	require.NoError(t, err)
+	assert.Equal(t, ts, stored)
+	assert.Equal(t, sample{Value: 1.5, Timestamp: 10}, v)
+
+	assert.NoError(t, s.Delete("key"))
+	assert.NoError(t, s.Delete("key"))
+	_, err = s.Get("key", &v)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestFileStore_SaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persist")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "nested", "integration.json")
+
+	s, err := NewFileStore(path, log.Discard, DefaultTTL)
+	require.NoError(t, err)
+	s.Set("key", sample{Value: 2, Timestamp: 20})
+	require.NoError(t, s.Save())
+
+	loaded, err := NewFileStore(path, log.Discard, DefaultTTL)
+	require.NoError(t, err)
+	var v sample
+	_, err = loaded.Get("key", &v)
+	require.NoError(t, err)
+	assert.Equal(t, sample{Value: 2, Timestamp: 20}, v)
+}
+
+func TestFileStore_DiscardsExpiredEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persist")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "integration.json")
+
+	s, err := NewFileStore(path, log.Discard, DefaultTTL)
+	require.NoError(t, err)
+	s.(*fileStore).now = func() time.Time { return time.Now().Add(-time.Hour) }
+	s.Set("old", 1)
+	s.(*fileStore).now = time.Now
+	s.Set("new", 2)
+	require.NoError(t, s.Save())
+
+	loaded, err := NewFileStore(path, log.Discard, DefaultTTL)
+	require.NoError(t, err)
+	var v int
+	_, err = loaded.Get("old", &v)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = loaded.Get("new", &v)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestFileStore_CorruptFileStartsEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persist")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "integration.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{corrupt"), 0644))
+
+	s, err := NewFileStore(path, log.Discard, DefaultTTL)
+	require.NoError(t, err)
+	var v int
+	_, err = s.Get("key", &v)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestDefaultPath(t *testing.T) {
+	assert.Equal(t, filepath.Join(os.TempDir(), "nr-integrations", "redis.json"), DefaultPath("redis"))
+}